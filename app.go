@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/controllers"
+	"xnok/slack-go-demo/dispatch"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// app is the controllers registered on the events of a client
+// and what they need to shut down
+type app struct {
+	router     *middleware.Router
+	grace      time.Duration
+	drain      *middleware.Drain
+	dispatcher *dispatch.Dispatcher
+	countdowns *services.CountdownService
+	cancel     context.CancelFunc
+	flushers   []flusher
+}
+
+// newApp loads the configuration and registers the controllers on the events of client
+// the first middlewares run before the ones of the App, e.g. the recorder
+func newApp(client *socketmode.Client, first ...middleware.Middleware) *app {
+	// Cache users and channels, the warm up happens in the background
+	directory := services.NewDirectory(client.GetApiClient(), services.DefaultDirectoryTTL)
+	go func() {
+		if err := directory.WarmUp(context.Background()); err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msg("Unable to warm up the directory")
+		}
+	}()
+
+	// Who is greeted when joining a channel and with which message
+	policy, err := services.LoadGreetingPolicy(os.Getenv("GREETING_POLICY_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the greeting policy")
+
+		os.Exit(1)
+	}
+	for _, template := range policy.Templates() {
+		if !views.HasGreetingTemplate(template) {
+			log.Error().
+				Str("template", template).
+				Msg("Unknown template in the greeting policy")
+
+			os.Exit(1)
+		}
+	}
+
+	// Onboarding sequence sent to new workspace members
+	onboarding, err := views.LoadOnboardingSchedule(os.Getenv("ONBOARDING_SCHEDULE_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the onboarding schedule")
+
+		os.Exit(1)
+	}
+
+	// Buddies assigned to new channel members
+	buddyConfig, err := services.LoadBuddyConfig(os.Getenv("BUDDY_POOLS_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the buddy pools")
+
+		os.Exit(1)
+	}
+	buddies := services.NewBuddyService(buddyConfig)
+
+	// Where the App answers when it is mentioned
+	replyMode, err := controllers.ParseReplyMode(os.Getenv("MENTION_REPLY_MODE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Invalid MENTION_REPLY_MODE")
+
+		os.Exit(1)
+	}
+
+	// Approve and deny decisions of rocket launches
+	decisions := services.NewDecisionLog()
+
+	// Who has to approve a rocket launch
+	approvalPolicy, err := services.LoadApprovalPolicy(os.Getenv("APPROVAL_POLICY_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the approval policy")
+
+		os.Exit(1)
+	}
+	approvals := services.NewLaunchApprovals(approvalPolicy, client.GetApiClient(), services.SystemClock{})
+
+	// Rockets that can be launched with /rocket
+	// its embedded frames are checked against the images of the App
+	catalog, err := services.LoadRocketCatalog(os.Getenv("ROCKET_CATALOG_FILE"), views.ImageAssets())
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Invalid rocket catalog")
+
+		os.Exit(1)
+	}
+
+	// Embedded images are uploaded to slack so the views do not depend on GitHub
+	assets := services.NewAssetPublisher(client.GetApiClient(), views.ImageAssets(), os.Getenv("ASSET_CACHE_FILE"))
+	views.UseAssets(assets)
+	views.UseAssetBaseURL(os.Getenv("ASSET_BASE_URL"))
+	go func() {
+		if err := assets.Publish(context.Background()); err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msg("Unable to upload the images")
+		}
+	}()
+
+	// Every launch, shown in the home tab and with /rocket stats
+	history := services.NewLaunchHistory()
+
+	// Launches announced later with /rocket at and /rocket in
+	scheduler := services.NewLaunchScheduler(services.SystemClock{})
+
+	// Rocket count downs tick every second and can be aborted
+	countdowns := services.NewCountdownService(services.SystemClock{}, time.Second)
+
+	// Who can run the slash commands and where
+	acls, err := commands.LoadACLs(os.Getenv("COMMAND_ACL_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the command ACLs")
+
+		os.Exit(1)
+	}
+
+	// How many handlers run at once and how many events wait for them
+	dispatchConfig, err := dispatch.LoadConfig(os.Getenv("DISPATCHER_CONFIG_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the dispatcher configuration")
+
+		os.Exit(1)
+	}
+	shardKey, err := middleware.ShardKey(dispatchConfig.ShardBy)
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Invalid dispatcher configuration")
+
+		os.Exit(1)
+	}
+	dispatcher := dispatch.NewDispatcher(dispatchConfig)
+	dispatcher.Start()
+
+	// Saved again on shutdown, the file stores already save every change so they survive a crash
+	flushers := []flusher{}
+
+	// Events redelivered by slack are handled once
+	var idempotency services.IdempotencyStore = services.NewMemoryIdempotencyStore(services.SystemClock{}, services.DefaultIdempotencyTTL)
+	if file := os.Getenv("IDEMPOTENCY_STORE_FILE"); file != "" {
+		store, err := services.NewFileIdempotencyStore(services.SystemClock{}, services.DefaultIdempotencyTTL, file)
+		if err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msg("Unable to load the idempotency store")
+
+			os.Exit(1)
+		}
+		idempotency = store
+		flushers = append(flushers, flusher{"idempotency store", store.Flush})
+	}
+
+	// Handlers failing with a transient error of the Slack API run again
+	retry, err := middleware.LoadRetryPolicy(os.Getenv("RETRY_POLICY_FILE"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to load the retry policy")
+
+		os.Exit(1)
+	}
+
+	// The events whose handler failed are kept to be replayed
+	var deadLetters services.DeadLetterStore = services.NewMemoryDeadLetterStore(services.DefaultDeadLetterLimit)
+	if file := os.Getenv("DEAD_LETTER_STORE_FILE"); file != "" {
+		store, err := services.NewFileDeadLetterStore(services.DefaultDeadLetterLimit, file)
+		if err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msg("Unable to load the dead letter store")
+
+			os.Exit(1)
+		}
+		deadLetters = store
+		flushers = append(flushers, flusher{"dead letter store", store.Flush})
+	}
+
+	// How long the handlers in flight can run once the App is asked to stop
+	grace, err := shutdownGracePeriod(os.Getenv("SHUTDOWN_GRACE_PERIOD"))
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Invalid SHUTDOWN_GRACE_PERIOD")
+
+		os.Exit(1)
+	}
+	drain := middleware.NewDrain()
+
+	// Inject Deps in router
+	socketmodeHandler := socketmode.NewsSocketmodeHandler(client)
+
+	// Every request is acknowledged once, before the deadline
+	acks := middleware.NewAckManager(services.SystemClock{}, middleware.DefaultAutoAckAfter)
+
+	// The event loop submits every handler to the worker pool in order, where it recovers from panics, skips redelivered events, is logged and timed
+	router := middleware.NewRouter(socketmodeHandler, first...)
+	router.Use(drain.Gate, acks.Track)
+	router.Dispatch(dispatcher, shardKey)
+	router.Use(
+		middleware.Recover,
+		middleware.Idempotent(idempotency),
+		middleware.Logging,
+		middleware.Timing(2*time.Second),
+	)
+	// The ContextHandlers resolve their user through the directory and are cancelled on shutdown
+	router.Users = directory
+	base, cancel := context.WithCancel(context.Background())
+	router.BaseContext = base
+	// The ContextHandlers failing with a transient error are retried, then dead lettered
+	router.Retry = retry
+	router.DeadLetters = deadLetters
+	slashCommands := controllers.NewSlashCommandRouter(router, acls)
+
+	// This if for Separate articles and demos. You can run there separatly or all together
+
+	// Build a Slack App Home in Golang Using Socket Mode
+	controllers.NewAppHomeController(router, buddies, history, directory)
+	// Properly Welcome Users in Slack with Golang using Socket Mode
+	controllers.NewGreetingController(router, directory, policy, onboarding, buddies, replyMode)
+	// Build Slack Slash Command in Golang Using Socket Mode
+	controllers.NewSlashCommandController(slashCommands, decisions, countdowns, approvals, scheduler, directory, history, catalog)
+	// Keep the cached users and channels up to date
+	controllers.NewDirectoryController(router, directory)
+	// List, inspect, replay or purge the events whose handler failed
+	controllers.NewDeadLetterController(slashCommands, middleware.DefaultRedactionRules())
+
+	return &app{
+		router:     router,
+		grace:      grace,
+		drain:      drain,
+		dispatcher: dispatcher,
+		countdowns: countdowns,
+		cancel:     cancel,
+		flushers:   flushers,
+	}
+
+}
+
+// Exit status of the App
+const (
+	// every handler in flight finished and everything was saved
+	exitOK = 0
+	// the App could not start, lost slack or could not save its state
+	exitFailure = 1
+	// the handlers in flight did not finish within the grace period
+	exitTimeout = 2
+)
+
+// defaultGracePeriod leaves time for a rocket count down to end
+const defaultGracePeriod = 30 * time.Second
+
+// flusher saves something that must survive a restart
+type flusher struct {
+	name  string
+	flush func() error
+}
+
+func shutdownGracePeriod(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultGracePeriod, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// shutdown stops accepting events, lets the handlers in flight and the count downs finish
+// within the grace period and saves the persistent stores, it returns the exit status
+func (a *app) shutdown() int {
+	status := exitOK
+
+	ctx, stop := context.WithTimeout(context.Background(), a.grace)
+	defer stop()
+
+	a.drain.Close()
+
+	err := a.drain.Wait(ctx)
+	if err == nil {
+		err = a.dispatcher.Shutdown(ctx)
+	}
+	if err == nil {
+		err = a.countdowns.Wait(ctx)
+	}
+	if err != nil {
+		log.Error().
+			Dur("grace_period", a.grace).
+			Msg("The handlers did not finish in time, cancelling them")
+		status = exitTimeout
+	}
+
+	// The handlers still running give up
+	a.cancel()
+
+	for _, f := range a.flushers {
+		if err := f.flush(); err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msgf("Unable to save the %s", f.name)
+			status = exitFailure
+		}
+	}
+
+	// the logs may be redirected to a file
+	os.Stderr.Sync()
+
+	log.Info().
+		Int("status", status).
+		Msg("Stopped")
+
+	return status
+}
@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Request is a slash command whose arguments are parsed
+// Context is the context of the handler, e.g. for middleware.Step
+type Request struct {
+	Context context.Context
+	Command slack.SlashCommand
+	Values  Values
+	Client  *socketmode.Client
+}
+
+// RunFunc runs a command, a UsageError is shown to the user with the usage
+type RunFunc func(req Request) error
+
+// Command is the declarative definition of a slash command or of one of its subcommands
+// The Spec of a subcommand is named after its parent, e.g. `/rocket list`
+// A command without Run only groups subcommands
+type Command struct {
+	Spec        Spec
+	Description string
+	ACL         ACL
+	Subcommands []*Command
+	Run         RunFunc
+}
+
+// Name is the word used to call the command, e.g. `list` for `/rocket list`
+func (c *Command) Name() string {
+	fields := strings.Fields(c.Spec.Command)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[len(fields)-1]
+}
+
+// Validate checks the definition before the command is registered
+func (c *Command) Validate() error {
+	if !strings.HasPrefix(c.Spec.Command, "/") {
+		return fmt.Errorf("command %q must start with /", c.Spec.Command)
+	}
+
+	return c.validate()
+}
+
+func (c *Command) validate() error {
+	if c.Run == nil && len(c.Subcommands) == 0 {
+		return fmt.Errorf("command %q has nothing to run", c.Spec.Command)
+	}
+
+	names := map[string]bool{"help": true}
+	for _, sub := range c.Subcommands {
+		if sub.Spec.Command != c.Spec.Command+" "+sub.Name() {
+			return fmt.Errorf("subcommand %q must be named after %q", sub.Spec.Command, c.Spec.Command)
+		}
+
+		key := strings.ToLower(sub.Name())
+		if names[key] {
+			return fmt.Errorf("subcommand %q is defined twice or is reserved", sub.Spec.Command)
+		}
+		names[key] = true
+
+		if err := sub.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Resolve finds the subcommand called by the text of a slash command
+// It returns the commands from c to the subcommand and the text left for its arguments
+func (c *Command) Resolve(text string) ([]*Command, string) {
+	path := []*Command{c}
+	current := c
+
+	for {
+		sub, rest := SplitSubCommand(text)
+		next, ok := current.subcommand(sub)
+		if !ok {
+			return path, strings.TrimSpace(text)
+		}
+
+		path = append(path, next)
+		current = next
+		text = rest
+	}
+}
+
+func (c *Command) subcommand(name string) (*Command, bool) {
+	for _, sub := range c.Subcommands {
+		if name != "" && strings.EqualFold(sub.Name(), name) {
+			return sub, true
+		}
+	}
+
+	return nil, false
+}
+
+// IsHelp tells if the arguments ask for the help of the command
+func IsHelp(args string) bool {
+	return args == "help" || args == "--help"
+}
+
+// HelpEntry is a line of the help of a command
+type HelpEntry struct {
+	Usage       string
+	Description string
+}
+
+// Help lists the usage of the command and of its subcommands
+func (c *Command) Help() []HelpEntry {
+	entries := []HelpEntry{}
+
+	if c.Run != nil {
+		entries = append(entries, HelpEntry{Usage: c.Spec.Usage(), Description: c.Description})
+	}
+
+	for _, sub := range c.Subcommands {
+		entries = append(entries, sub.Help()...)
+	}
+
+	return entries
+}
+
+// ManifestCommand describes a slash command the way the App manifest does
+// e.g. features.slash_commands in manifest.yml
+type ManifestCommand struct {
+	Command      string `json:"command"`
+	Description  string `json:"description"`
+	UsageHint    string `json:"usage_hint,omitempty"`
+	ShouldEscape bool   `json:"should_escape"`
+}
+
+// Manifest is the metadata of the command for the App manifest
+// The usage hint lists the arguments and the subcommands, e.g. `[count] [--silent] | list | help`
+func (c *Command) Manifest() ManifestCommand {
+	hints := []string{}
+
+	if c.Run != nil {
+		if args := strings.TrimSpace(strings.TrimPrefix(c.Spec.Usage(), c.Spec.Command)); args != "" {
+			hints = append(hints, args)
+		}
+	}
+
+	for _, sub := range c.Subcommands {
+		hints = append(hints, sub.Name())
+	}
+	hints = append(hints, "help")
+
+	return ManifestCommand{
+		Command:     c.Spec.Command,
+		Description: c.Description,
+		UsageHint:   strings.Join(hints, " | "),
+	}
+}
+
+// SplitSubCommand separates the first word of the command line from its arguments
+func SplitSubCommand(text string) (string, string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	return fields[0], strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+}
+
+// UserGroupMembersGetter is the part of the slack API we need to check user groups
+type UserGroupMembersGetter interface {
+	GetUserGroupMembers(userGroup string) ([]string, error)
+}
+
+// ACL restricts who can run a command and where
+// Without users nor user groups anybody can run it, without channels it runs everywhere
+type ACL struct {
+	Users      []string `json:"users,omitempty"`
+	Channels   []string `json:"channels,omitempty"`
+	UserGroups []string `json:"user_groups,omitempty"`
+	// DenyAll refuses everybody, e.g. the admin commands until their ACL is configured
+	DenyAll bool `json:"-"`
+}
+
+// ACLs are the ACL of the commands by name, e.g. `/rocket stats`
+type ACLs map[string]ACL
+
+// LoadACLs reads the ACL file of the commands
+// When no file is provided the ACLs of the definitions are used
+func LoadACLs(file string) (ACLs, error) {
+	acls := ACLs{}
+	if file == "" {
+		return acls, nil
+	}
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return acls, err
+	}
+
+	if err := json.Unmarshal(str, &acls); err != nil {
+		return acls, err
+	}
+
+	for command := range acls {
+		if !strings.HasPrefix(command, "/") {
+			return acls, fmt.Errorf("command %q must start with /", command)
+		}
+	}
+
+	return acls, nil
+}
+
+// Apply replaces the ACL of the commands found in acls, a configured ACL lifts DenyAll
+func (a ACLs) Apply(command *Command) {
+	if acl, ok := a[command.Spec.Command]; ok {
+		command.ACL = acl
+	}
+
+	for _, sub := range command.Subcommands {
+		a.Apply(sub)
+	}
+}
+
+// Allows checks a user can run the command in a channel
+func (a ACL) Allows(user string, channel string, groups UserGroupMembersGetter) (bool, error) {
+	if a.DenyAll {
+		return false, nil
+	}
+
+	if len(a.Channels) > 0 && !contains(a.Channels, channel) {
+		return false, nil
+	}
+
+	if len(a.Users) == 0 && len(a.UserGroups) == 0 {
+		return true, nil
+	}
+
+	if contains(a.Users, user) {
+		return true, nil
+	}
+
+	for _, group := range a.UserGroups {
+		members, err := groups.GetUserGroupMembers(group)
+		if err != nil {
+			return false, err
+		}
+
+		if contains(members, user) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func run(req Request) error { return nil }
+
+func testCommand() *Command {
+	return &Command{
+		Spec:        Spec{Command: "/rocket", Args: []Arg{{Name: "count", Type: TypeInt}}},
+		Description: "Launch a rocket",
+		Run:         run,
+		Subcommands: []*Command{
+			{Spec: Spec{Command: "/rocket list"}, Description: "List the launches", Run: run},
+			{
+				Spec: Spec{Command: "/rocket admin"},
+				Subcommands: []*Command{
+					{Spec: Spec{Command: "/rocket admin purge", Args: []Arg{{Name: "id", Required: true}}}, Description: "Purge a launch", Run: run},
+				},
+			},
+		},
+	}
+}
+
+func TestCommand_Resolve(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantPath []string
+		wantArgs string
+	}{
+		{text: "", wantPath: []string{"/rocket"}, wantArgs: ""},
+		{text: "10 --silent", wantPath: []string{"/rocket"}, wantArgs: "10 --silent"},
+		{text: "LIST", wantPath: []string{"/rocket", "/rocket list"}, wantArgs: ""},
+		{text: "admin purge 3", wantPath: []string{"/rocket", "/rocket admin", "/rocket admin purge"}, wantArgs: "3"},
+		{text: "admin help", wantPath: []string{"/rocket", "/rocket admin"}, wantArgs: "help"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			path, args := testCommand().Resolve(tt.text)
+
+			got := []string{}
+			for _, c := range path {
+				got = append(got, c.Spec.Command)
+			}
+
+			if diff := deep.Equal(got, tt.wantPath); diff != nil {
+				t.Error(diff)
+			}
+			if args != tt.wantArgs {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCommand_Validate(t *testing.T) {
+	if err := testCommand().Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	misnamed := testCommand()
+	misnamed.Subcommands[0].Spec.Command = "/list"
+	if err := misnamed.Validate(); err == nil {
+		t.Error("Validate() accepted a subcommand not named after its parent")
+	}
+
+	reserved := testCommand()
+	reserved.Subcommands[0].Spec.Command = "/rocket help"
+	if err := reserved.Validate(); err == nil {
+		t.Error("Validate() accepted a help subcommand")
+	}
+
+	empty := &Command{Spec: Spec{Command: "/rocket"}}
+	if err := empty.Validate(); err == nil {
+		t.Error("Validate() accepted a command with nothing to run")
+	}
+}
+
+func TestCommand_Help(t *testing.T) {
+	want := []HelpEntry{
+		{Usage: "/rocket [count]", Description: "Launch a rocket"},
+		{Usage: "/rocket list", Description: "List the launches"},
+		{Usage: "/rocket admin purge <id>", Description: "Purge a launch"},
+	}
+
+	if diff := deep.Equal(testCommand().Help(), want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestCommand_Manifest(t *testing.T) {
+	want := ManifestCommand{
+		Command:     "/rocket",
+		Description: "Launch a rocket",
+		UsageHint:   "[count] | list | admin | help",
+	}
+
+	if diff := deep.Equal(testCommand().Manifest(), want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+type fakeGroups map[string][]string
+
+func (f fakeGroups) GetUserGroupMembers(group string) ([]string, error) {
+	members, ok := f[group]
+	if !ok {
+		return nil, errors.New("no_such_subteam")
+	}
+	return members, nil
+}
+
+func TestACL_Allows(t *testing.T) {
+	groups := fakeGroups{"S1": {"U2"}}
+
+	tests := []struct {
+		name    string
+		acl     ACL
+		user    string
+		channel string
+		want    bool
+		wantErr bool
+	}{
+		{name: "Anybody anywhere", acl: ACL{}, user: "U1", channel: "C1", want: true},
+		{name: "Nobody", acl: ACL{DenyAll: true}, user: "U1", channel: "C1", want: false},
+		{name: "Allowed channel", acl: ACL{Channels: []string{"C1"}}, user: "U1", channel: "C1", want: true},
+		{name: "Other channel", acl: ACL{Channels: []string{"C1"}}, user: "U1", channel: "C2", want: false},
+		{name: "Allowed user", acl: ACL{Users: []string{"U1"}, UserGroups: []string{"S1"}}, user: "U1", channel: "C1", want: true},
+		{name: "Member of a group", acl: ACL{Users: []string{"U1"}, UserGroups: []string{"S1"}}, user: "U2", channel: "C1", want: true},
+		{name: "Other user", acl: ACL{Users: []string{"U1"}, UserGroups: []string{"S1"}}, user: "U3", channel: "C1", want: false},
+		{name: "Unknown group", acl: ACL{UserGroups: []string{"S2"}}, user: "U3", channel: "C1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.acl.Allows(tt.user, tt.channel, groups)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Allows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadACLs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "acls.json")
+	ioutil.WriteFile(file, []byte(`{"/rocket admin purge": {"users": ["U1"]}}`), 0644)
+
+	acls, err := LoadACLs(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	command := testCommand()
+	acls.Apply(command)
+
+	if diff := deep.Equal(command.Subcommands[1].Subcommands[0].ACL, ACL{Users: []string{"U1"}}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(command.ACL, ACL{}); diff != nil {
+		t.Error(diff)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	ioutil.WriteFile(invalid, []byte(`{"rocket": {}}`), 0644)
+	if _, err := LoadACLs(invalid); err == nil {
+		t.Error("LoadACLs() accepted a command without /")
+	}
+}
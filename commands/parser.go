@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType is the type an argument or a flag is converted to
+type ValueType int
+
+const (
+	TypeString ValueType = iota
+	TypeInt
+	TypeBool
+	TypeDuration
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeInt:
+		return "number"
+	case TypeBool:
+		return "bool"
+	case TypeDuration:
+		return "duration"
+	}
+
+	return "text"
+}
+
+// Arg is a positional argument, e.g. `10` in `/rocket 10`
+type Arg struct {
+	Name     string
+	Type     ValueType
+	Default  interface{}
+	Required bool
+	Usage    string
+}
+
+// Flag is a named argument, e.g. `--rocket "Starship"` or `--silent`
+// Bool flags do not need a value
+type Flag struct {
+	Name    string
+	Type    ValueType
+	Default interface{}
+	Usage   string
+}
+
+// Spec describes the arguments accepted by a slash command
+type Spec struct {
+	Command string
+	Args    []Arg
+	Flags   []Flag
+}
+
+// UsageError is returned when the command line does not match the Spec
+// It can be displayed as is to the user
+type UsageError struct {
+	Reason string
+	Usage  string
+}
+
+func (e *UsageError) Error() string {
+	return e.Reason + "\nUsage: " + e.Usage
+}
+
+// Values holds the parsed arguments and flags
+type Values struct {
+	values map[string]interface{}
+	set    map[string]bool
+}
+
+// IsSet tells if the value was provided on the command line
+func (v Values) IsSet(name string) bool {
+	return v.set[name]
+}
+
+func (v Values) String(name string) string {
+	s, _ := v.values[name].(string)
+	return s
+}
+
+func (v Values) Int(name string) int {
+	i, _ := v.values[name].(int)
+	return i
+}
+
+func (v Values) Bool(name string) bool {
+	b, _ := v.values[name].(bool)
+	return b
+}
+
+func (v Values) Duration(name string) time.Duration {
+	d, _ := v.values[name].(time.Duration)
+	return d
+}
+
+// Usage is a one line description of the command, e.g. `/rocket [count] [--silent]`
+func (s Spec) Usage() string {
+	parts := []string{s.Command}
+
+	for _, arg := range s.Args {
+		if arg.Required {
+			parts = append(parts, "<"+arg.Name+">")
+		} else {
+			parts = append(parts, "["+arg.Name+"]")
+		}
+	}
+
+	for _, flag := range s.Flags {
+		if flag.Type == TypeBool {
+			parts = append(parts, "[--"+flag.Name+"]")
+		} else {
+			parts = append(parts, "[--"+flag.Name+" "+flag.Type.String()+"]")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (s Spec) usageError(format string, a ...interface{}) *UsageError {
+	return &UsageError{
+		Reason: fmt.Sprintf(format, a...),
+		Usage:  s.Usage(),
+	}
+}
+
+// Parse converts the text of a slash command into typed values
+func (s Spec) Parse(text string) (Values, error) {
+	values := Values{
+		values: make(map[string]interface{}),
+		set:    make(map[string]bool),
+	}
+
+	// Start with the defaults
+	for _, arg := range s.Args {
+		values.values[arg.Name] = arg.Default
+	}
+	for _, flag := range s.Flags {
+		values.values[flag.Name] = flag.Default
+	}
+
+	tokens, err := Tokenize(text)
+	if err != nil {
+		return values, s.usageError("%v", err)
+	}
+
+	position := 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		// Positional argument
+		if !strings.HasPrefix(token, "--") {
+			if position >= len(s.Args) {
+				return values, s.usageError("unexpected argument %q", token)
+			}
+
+			arg := s.Args[position]
+			value, err := convert(arg.Type, token)
+			if err != nil {
+				return values, s.usageError("invalid %s: %v", arg.Name, err)
+			}
+
+			values.values[arg.Name] = value
+			values.set[arg.Name] = true
+			position++
+			continue
+		}
+
+		// Flag, either `--name value`, `--name=value` or `--name` for bool flags
+		name := strings.TrimPrefix(token, "--")
+		raw, hasValue := "", false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, raw, hasValue = name[:j], name[j+1:], true
+		}
+
+		flag, ok := s.flag(name)
+		if !ok {
+			return values, s.usageError("unknown flag --%s", name)
+		}
+
+		if !hasValue {
+			if flag.Type == TypeBool {
+				raw = "true"
+			} else if i+1 < len(tokens) {
+				i++
+				raw = tokens[i]
+			} else {
+				return values, s.usageError("flag --%s needs a value", name)
+			}
+		}
+
+		value, err := convert(flag.Type, raw)
+		if err != nil {
+			return values, s.usageError("invalid --%s: %v", name, err)
+		}
+
+		values.values[flag.Name] = value
+		values.set[flag.Name] = true
+	}
+
+	for _, arg := range s.Args[position:] {
+		if arg.Required {
+			return values, s.usageError("missing %s", arg.Name)
+		}
+	}
+
+	return values, nil
+}
+
+func (s Spec) flag(name string) (Flag, bool) {
+	for _, flag := range s.Flags {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+
+	return Flag{}, false
+}
+
+func convert(t ValueType, raw string) (interface{}, error) {
+	switch t {
+	case TypeInt:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return i, nil
+	case TypeBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", raw)
+		}
+		return b, nil
+	case TypeDuration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a duration (e.g. 90s, 2h30m)", raw)
+		}
+		return d, nil
+	}
+
+	return raw, nil
+}
+
+// Tokenize splits a command line on spaces, double quoted strings are kept together
+// Slack clients may send smart quotes so we accept them as well
+func Tokenize(text string) ([]string, error) {
+	tokens := []string{}
+
+	var current strings.Builder
+	var quote rune
+	inToken := false
+
+	for _, r := range text {
+		switch {
+		case quote != 0 && closes(quote, r):
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '“':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %q", string(quote))
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+func closes(open rune, r rune) bool {
+	if open == '“' {
+		return r == '”'
+	}
+
+	return r == open
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Empty", text: "", want: []string{}},
+		{name: "Spaces", text: "  10   --silent ", want: []string{"10", "--silent"}},
+		{name: "Quoted string", text: `10 --rocket "Falcon Heavy"`, want: []string{"10", "--rocket", "Falcon Heavy"}},
+		{name: "Smart quotes", text: `--rocket “Falcon Heavy”`, want: []string{"--rocket", "Falcon Heavy"}},
+		{name: "Empty quoted string", text: `--rocket ""`, want: []string{"--rocket", ""}},
+		{name: "Apostrophe", text: `--rocket Bob's`, want: []string{"--rocket", "Bob's"}},
+		{name: "Unterminated quote", text: `--rocket "Falcon`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Tokenize(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Tokenize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); !tt.wantErr && diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+var testSpec = Spec{
+	Command: "/rocket",
+	Args: []Arg{
+		{Name: "count", Type: TypeInt, Default: 3},
+	},
+	Flags: []Flag{
+		{Name: "rocket", Type: TypeString, Default: "Falcon 9"},
+		{Name: "silent", Type: TypeBool, Default: false},
+		{Name: "delay", Type: TypeDuration, Default: time.Duration(0)},
+	},
+}
+
+func TestSpec_Parse(t *testing.T) {
+	type want struct {
+		count  int
+		rocket string
+		silent bool
+		delay  time.Duration
+	}
+	tests := []struct {
+		name    string
+		text    string
+		want    want
+		wantErr string
+	}{
+		{
+			name: "Defaults",
+			text: "",
+			want: want{count: 3, rocket: "Falcon 9"},
+		},
+		{
+			name: "Positional and flags",
+			text: `10 --rocket "Starship" --silent`,
+			want: want{count: 10, rocket: "Starship", silent: true},
+		},
+		{
+			name: "Flags before positional",
+			text: `--silent=false --delay 1m30s 5`,
+			want: want{count: 5, rocket: "Falcon 9", delay: 90 * time.Second},
+		},
+		{
+			name:    "Invalid number",
+			text:    "ten",
+			wantErr: "invalid count: \"ten\" is not a number\nUsage: /rocket [count] [--rocket text] [--silent] [--delay duration]",
+		},
+		{
+			name:    "Unknown flag",
+			text:    "--fast",
+			wantErr: "unknown flag --fast\nUsage: /rocket [count] [--rocket text] [--silent] [--delay duration]",
+		},
+		{
+			name:    "Missing flag value",
+			text:    "--rocket",
+			wantErr: "flag --rocket needs a value\nUsage: /rocket [count] [--rocket text] [--silent] [--delay duration]",
+		},
+		{
+			name:    "Too many arguments",
+			text:    "3 4",
+			wantErr: "unexpected argument \"4\"\nUsage: /rocket [count] [--rocket text] [--silent] [--delay duration]",
+		},
+		{
+			name:    "Invalid duration",
+			text:    "--delay soon",
+			wantErr: "invalid --delay: \"soon\" is not a duration (e.g. 90s, 2h30m)\nUsage: /rocket [count] [--rocket text] [--silent] [--delay duration]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := testSpec.Parse(tt.text)
+			if tt.wantErr != "" {
+				if _, ok := err.(*UsageError); !ok {
+					t.Fatalf("Parse() error = %v, want a UsageError", err)
+				}
+				if err.Error() != tt.wantErr {
+					t.Errorf("Parse() error = %q, want %q", err.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			got := want{
+				count:  values.Int("count"),
+				rocket: values.String("rocket"),
+				silent: values.Bool("silent"),
+				delay:  values.Duration("delay"),
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpec_Usage(t *testing.T) {
+	spec := Spec{
+		Command: "/remind",
+		Args: []Arg{
+			{Name: "who", Type: TypeString, Required: true},
+			{Name: "what", Type: TypeString},
+		},
+	}
+
+	if got, want := spec.Usage(), "/remind <who> [what]"; got != want {
+		t.Errorf("Usage() = %v, want %v", got, want)
+	}
+
+	if _, err := spec.Parse(""); err == nil || err.(*UsageError).Reason != "missing who" {
+		t.Errorf("Parse() error = %v, want missing who", err)
+	}
+}
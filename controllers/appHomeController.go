@@ -2,10 +2,14 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"reflect"
 	"time"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
 	"xnok/slack-go-demo/views"
 
 	"github.com/slack-go/slack"
@@ -13,14 +17,23 @@ import (
 	"github.com/slack-go/slack/socketmode"
 )
 
+// publishTimeout bounds the handlers publishing the App Home
+const publishTimeout = 5 * time.Second
+
 // We create a sctucture to let us use dependency injection
 type AppHomeController struct {
-	EventHandler *socketmode.SocketmodeHandler
+	EventHandler *middleware.Router
+	Buddies      *services.BuddyService
+	History      *services.LaunchHistory
+	Directory    *services.Directory
 }
 
-func NewAppHomeController(eventhandler *socketmode.SocketmodeHandler) AppHomeController {
+func NewAppHomeController(eventhandler *middleware.Router, buddies *services.BuddyService, history *services.LaunchHistory, directory *services.Directory) AppHomeController {
 	c := AppHomeController{
 		EventHandler: eventhandler,
+		Buddies:      buddies,
+		History:      history,
+		Directory:    directory,
 	}
 
 	c.EventHandler.Handle(socketmode.EventTypeErrorBadMessage, c.recoverAppHomeOpened)
@@ -28,19 +41,23 @@ func NewAppHomeController(eventhandler *socketmode.SocketmodeHandler) AppHomeCon
 	// App Home (2)
 	c.EventHandler.HandleEventsAPI(
 		slackevents.AppHomeOpened,
-		c.publishHomeTabView,
+		c.EventHandler.WithContext(c.publishHomeTabView, publishTimeout),
+		middleware.AutoAck,
 	)
 
 	// Create Stickie note Triggered (12)
 	c.EventHandler.HandleInteractionBlockAction(
 		views.AddStockieNoteActionID,
-		c.openCreateStickieNoteModal,
+		c.EventHandler.WithContext(c.openCreateStickieNoteModal, 0),
+		middleware.AutoAck,
 	)
 
 	// Create Stickie note Submitted (22)
 	c.EventHandler.HandleInteraction(
 		slack.InteractionTypeViewSubmission,
-		c.createStickieNote,
+		c.EventHandler.WithContext(c.createStickieNote, publishTimeout),
+		viewSubmission(views.CreateStickieNoteCallbackID),
+		middleware.AutoAck,
 	)
 
 	return c
@@ -103,18 +120,21 @@ func (c *AppHomeController) recoverAppHomeOpened(evt *socketmode.Event, clt *soc
 		Request: &socketmode.Request{ //  we need to attach the envelope ID for the request to Ack
 			Type:       "events_api",
 			EnvelopeID: hE.Envelope,
+			Payload:    hE.Payload, // the fixed payload, so a failed handler can be replayed
 		},
 	}
 
-	c.EventHandler.Client.Events <- fabEvent
+	c.EventHandler.Client().Events <- fabEvent
 }
 
-func (c *AppHomeController) publishHomeTabView(evt *socketmode.Event, clt *socketmode.Client) {
+func (c *AppHomeController) publishHomeTabView(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	logger := middleware.Logger(ctx)
+
 	// we need to cast our socketmode.Event into slackevents.AppHomeOpenedEvent
 	evt_api, ok := evt.Data.(slackevents.EventsAPIEvent)
 
 	if ok != true {
-		log.Printf("ERROR converting event to slackevents.EventsAPIEvent")
+		logger.Printf("ERROR converting event to slackevents.EventsAPIEvent")
 	}
 
 	evt_app_home_opened, ok := evt_api.InnerEvent.Data.(slackevents.AppHomeOpenedEvent)
@@ -122,35 +142,29 @@ func (c *AppHomeController) publishHomeTabView(evt *socketmode.Event, clt *socke
 	var user string
 
 	if ok != true {
-		log.Printf("ERROR converting inner event to slackevents.AppHomeOpenedEvent")
+		logger.Printf("ERROR converting inner event to slackevents.AppHomeOpenedEvent")
 		//Patch the fact that we are not able to cast evt_api.InnerEvent.Data to AppHomeOpenedEvent
 		user = reflect.ValueOf(evt_api.InnerEvent.Data).Elem().FieldByName("User").Interface().(string)
 	} else {
 		user = evt_app_home_opened.User
 	}
 
-	log.Printf("ERROR publishHomeTabView: %v", evt_app_home_opened)
-
 	// create the view using block-kit
 	view := views.AppHomeTabView()
+	c.appendMentees(user, &view)
+	c.appendLaunches(user, clt, &view)
 
 	// Publish the view (3)
-	// We get the Api client from `clt` and post our view
-	_, err := clt.GetApiClient().PublishView(user, view, "")
+	// We get the Api client from `clt` and post our view, it gives up when ctx is done
+	_, err := clt.GetApiClient().PublishViewContext(ctx, user, view, "")
 
-	//Handle errors
-	if err != nil {
-		log.Printf("ERROR publishHomeTabView: %v", err)
-	}
+	return err
 }
 
-func (c *AppHomeController) openCreateStickieNoteModal(evt *socketmode.Event, clt *socketmode.Client) {
+func (c *AppHomeController) openCreateStickieNoteModal(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
 	// we need to cast our socketmode.Event
 	interaction := evt.Data.(slack.InteractionCallback)
 
-	// Make sure to respond to the server to avoid an error
-	clt.Ack(*evt.Request)
-
 	// create the view using block-kit
 	view := views.CreateStickieNoteModal()
 
@@ -159,17 +173,16 @@ func (c *AppHomeController) openCreateStickieNoteModal(evt *socketmode.Event, cl
 
 	//Handle errors
 	if err != nil {
-		log.Printf("ERROR openCreateStickieNoteModal: %v", err)
+		return fmt.Errorf("openCreateStickieNoteModal: %w", err)
 	}
+
+	return nil
 }
 
-func (c *AppHomeController) createStickieNote(evt *socketmode.Event, clt *socketmode.Client) {
+func (c *AppHomeController) createStickieNote(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
 	// we need to cast our socketmode.Event into slack.InteractionCallback
 	view_submission := evt.Data.(slack.InteractionCallback)
 
-	// Make sure to respond to the server to avoid an error
-	clt.Ack(*evt.Request)
-
 	// Create the model
 	note := views.StickieNote{
 		Description: view_submission.View.State.Values[views.ModalDescriptionBlockID][views.ModalDescriptionActionID].Value,
@@ -179,13 +192,41 @@ func (c *AppHomeController) createStickieNote(evt *socketmode.Event, clt *socket
 
 	// create the view using block-kit
 	view := views.AppHomeCreateStickieNote(note)
+	c.appendMentees(view_submission.User.ID, &view)
+	c.appendLaunches(view_submission.User.ID, clt, &view)
 
 	// Publish the view (23)
 	// We get the Api client from `clt` and post our view
-	_, err := clt.GetApiClient().PublishView(view_submission.User.ID, view, "")
+	_, err := clt.GetApiClient().PublishViewContext(ctx, view_submission.User.ID, view, "")
 
-	//Handle errors
-	if err != nil {
-		log.Printf("ERROR createStickieNote: %v", err)
+	return err
+}
+
+// appendMentees adds the new members followed by the user to the home tab
+func (c *AppHomeController) appendMentees(user string, view *slack.HomeTabViewRequest) {
+	if c.Buddies == nil {
+		return
+	}
+
+	mentees := []views.Mentee{}
+	for _, p := range c.Buddies.Mentees(user) {
+		mentees = append(mentees, views.Mentee{
+			User:    p.Mentee,
+			Channel: p.Channel,
+			Since:   p.Since.Format("Jan 2, 2006"),
+		})
+	}
+
+	view.Blocks.BlockSet = append(view.Blocks.BlockSet, views.AppHomeMenteesBlocks(mentees)...)
+}
+
+// appendLaunches adds the recent rocket launches and the leaderboards the user can see to the home tab
+func (c *AppHomeController) appendLaunches(user string, clt *socketmode.Client, view *slack.HomeTabViewRequest) {
+	if c.History == nil || c.Directory == nil {
+		return
 	}
+
+	recent, users, channels := launchStats(c.History, homeTabLaunches, launchAudience(user, c.Directory, clt.GetApiClient()))
+
+	view.Blocks.BlockSet = append(view.Blocks.BlockSet, views.AppHomeLaunchesBlocks(recent, users, channels)...)
 }
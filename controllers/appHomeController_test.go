@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"os"
 	"testing"
 	"xnok/slack-go-demo/drivers"
@@ -15,7 +16,7 @@ import (
 func setup_slacktest() (*slacktest.Server, *slack.Client) {
 	// Set up the test server.
 	testServer := slacktest.NewTestServer()
-	go testServer.Start()
+	testServer.Start()
 
 	// Setup and start the RTM.
 	api := slack.New("ABCD", slack.OptionAPIURL(testServer.GetAPIURL()))
@@ -64,7 +65,7 @@ func TestAppHomeController_publishHomeTabView(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.c.publishHomeTabView(tt.args.evt, tt.args.clt)
+			tt.c.publishHomeTabView(context.Background(), tt.args.evt, tt.args.clt)
 		})
 	}
 }
@@ -77,7 +78,10 @@ func TestAppHomeController_publishHomeTabView_Integration(t *testing.T) {
 	// Use an SLACK_BOT_TOKEN, SLACK_APP_TOKEN, TEST_USER to do our test
 	godotenv.Load("../test_slack.env")
 
-	soccketClient, _ := drivers.ConnectToSlackViaSocketmode()
+	soccketClient, err := drivers.ConnectToSlackViaSocketmode()
+	if err != nil {
+		t.Skipf("Unable to connect to slack: %v", err)
+	}
 	user := os.Getenv("TEST_USER")
 
 	type args struct {
@@ -111,7 +115,7 @@ func TestAppHomeController_publishHomeTabView_Integration(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.c.publishHomeTabView(tt.args.evt, tt.args.clt)
+			tt.c.publishHomeTabView(context.Background(), tt.args.evt, tt.args.clt)
 		})
 	}
 }
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/slack-go/slack"
+)
+
+var deadLetterIDArg = commands.Arg{Name: "id", Type: commands.TypeString, Required: true, Usage: "ID of the failed event"}
+
+var deadLetterCommand = commands.Spec{
+	Command: "/deadletter",
+}
+
+var deadLetterListCommand = commands.Spec{
+	Command: "/deadletter list",
+}
+
+var deadLetterInspectCommand = commands.Spec{
+	Command: "/deadletter inspect",
+	Args:    []commands.Arg{deadLetterIDArg},
+}
+
+var deadLetterReplayCommand = commands.Spec{
+	Command: "/deadletter replay",
+	Args:    []commands.Arg{deadLetterIDArg},
+}
+
+var deadLetterPurgeCommand = commands.Spec{
+	Command: "/deadletter purge",
+	Args: []commands.Arg{
+		{Name: "id", Type: commands.TypeString, Default: "", Usage: "ID of the failed event, all of them by default"},
+	},
+}
+
+// maxDeadLettersShown keeps the list under the block limit of slack
+const maxDeadLettersShown = 20
+
+// DeadLetterController lets the admins list, inspect, replay or purge the events whose handler failed
+// The envelopes may hold personal information, nobody can run the command until its ACL is configured
+type DeadLetterController struct {
+	EventHandler *middleware.Router
+	Commands     *SlashCommandRouter
+	// Redaction hides the personal information of the inspected envelopes
+	Redaction middleware.RedactionRules
+}
+
+func NewDeadLetterController(router *SlashCommandRouter, redaction middleware.RedactionRules) DeadLetterController {
+	c := DeadLetterController{
+		EventHandler: router.EventHandler,
+		Commands:     router,
+		Redaction:    redaction,
+	}
+
+	// Register the command /deadletter and its subcommands
+	c.Commands.Register(c.deadLetterCommands())
+
+	return c
+}
+
+// deadLetterCommands defines /deadletter and its subcommands
+func (c DeadLetterController) deadLetterCommands() *commands.Command {
+	return &commands.Command{
+		Spec:        deadLetterCommand,
+		Description: "Manage the events whose handler failed",
+		ACL:         commands.ACL{DenyAll: true},
+		Subcommands: []*commands.Command{
+			{Spec: deadLetterListCommand, Description: "List the most recent failed events", Run: c.listDeadLetters},
+			{Spec: deadLetterInspectCommand, Description: "Show a failed event and its envelope", Run: c.inspectDeadLetter},
+			{Spec: deadLetterReplayCommand, Description: "Run the handler of a failed event again", Run: c.replayDeadLetter},
+			{Spec: deadLetterPurgeCommand, Description: "Forget a failed event or all of them", Run: c.purgeDeadLetters},
+		},
+	}
+}
+
+// listDeadLetters shows the most recent failed events first
+func (c DeadLetterController) listDeadLetters(req commands.Request) error {
+	letters, err := c.store().List()
+	if err != nil {
+		return err
+	}
+
+	shown := []views.DeadLetter{}
+	for i := len(letters) - 1; i >= 0 && len(shown) < maxDeadLettersShown; i-- {
+		shown = append(shown, deadLetterView(letters[i]))
+	}
+
+	return c.reply(req, views.DeadLetters(shown, len(letters)))
+}
+
+// inspectDeadLetter shows a failed event and its envelope without the personal information
+func (c DeadLetterController) inspectDeadLetter(req commands.Request) error {
+	letter, err := c.store().Get(req.Values.String("id"))
+	if err == services.ErrDeadLetterNotFound {
+		return &commands.UsageError{Reason: err.Error(), Usage: deadLetterInspectCommand.Usage()}
+	}
+	if err != nil {
+		return err
+	}
+
+	envelope := "no envelope"
+	if len(letter.Envelope) > 0 {
+		redacted, err := c.Redaction.Redact(letter.Envelope)
+		if err != nil {
+			return err
+		}
+		envelope = string(redacted)
+	}
+
+	return c.reply(req, views.DeadLetterDetails(deadLetterView(letter), envelope))
+}
+
+// replayDeadLetter runs the handler of a failed event again, it is forgotten once it succeeds
+func (c DeadLetterController) replayDeadLetter(req commands.Request) error {
+	id := req.Values.String("id")
+
+	err := c.EventHandler.Replay(id, req.Client)
+	if err == services.ErrDeadLetterNotFound {
+		return &commands.UsageError{Reason: err.Error(), Usage: deadLetterReplayCommand.Usage()}
+	}
+	if err != nil {
+		return c.reply(req, views.DeadLetterReplayFailed(id, err.Error()))
+	}
+
+	return c.reply(req, views.DeadLetterReplayed(id))
+}
+
+// purgeDeadLetters forgets a failed event, or all of them
+func (c DeadLetterController) purgeDeadLetters(req commands.Request) error {
+	id := req.Values.String("id")
+	if id == "" {
+		count, err := c.store().Purge()
+		if err != nil {
+			return err
+		}
+
+		return c.reply(req, views.DeadLettersPurged(count))
+	}
+
+	err := c.store().Delete(id)
+	if err == services.ErrDeadLetterNotFound {
+		return &commands.UsageError{Reason: err.Error(), Usage: deadLetterPurgeCommand.Usage()}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.reply(req, views.DeadLettersPurged(1))
+}
+
+// store keeps the failed events of the routes
+func (c DeadLetterController) store() services.DeadLetterStore {
+	if c.EventHandler.DeadLetters == nil {
+		return services.NewMemoryDeadLetterStore(0)
+	}
+
+	return c.EventHandler.DeadLetters
+}
+
+// reply answers the admin with an ephemeral message
+func (c DeadLetterController) reply(req commands.Request, blocks []slack.Block) error {
+	command, clt := req.Command, req.Client
+
+	_, _, err := clt.GetApiClient().PostMessage(
+		command.ChannelID,
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	return err
+}
+
+func deadLetterView(letter services.DeadLetter) views.DeadLetter {
+	return views.DeadLetter{
+		ID:       letter.ID,
+		Time:     letter.Time,
+		Event:    letter.Event,
+		Handler:  letter.Handler,
+		Error:    letter.Error,
+		Attempts: letter.Attempts,
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestDeadLetterController(t *testing.T) {
+
+	// The answers are sent through the response URL
+	var mu sync.Mutex
+	responses := []string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		responses = append(responses, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	soccketClient := socketmode.New(slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/")))
+
+	eventHandler := middleware.NewRouter(socketmode.NewsSocketmodeHandler(soccketClient))
+	eventHandler.DeadLetters = services.NewMemoryDeadLetterStore(services.DefaultDeadLetterLimit)
+
+	// Only the admin can manage the failed events
+	router := NewSlashCommandRouter(eventHandler, commands.ACLs{"/deadletter": {Users: []string{"U0123456"}}})
+	NewDeadLetterController(router, middleware.DefaultRedactionRules())
+
+	// A handler fails until slack is back
+	var failure error = errors.New("internal_error")
+	failing := eventHandler.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		return failure
+	}, 0)
+
+	envelope := json.RawMessage(`{"envelope_id":"e1","type":"slash_commands","payload":{"command":"/rocket","user_name":"jane@example.com"}}`)
+	evt, _ := middleware.ParseEnvelope(envelope)
+	failing(evt, soccketClient)
+
+	letters, _ := eventHandler.DeadLetters.List()
+	if len(letters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(letters))
+	}
+	id := letters[0].ID
+
+	command := func(router *SlashCommandRouter, text string) string {
+		t.Helper()
+
+		router.Handle(context.Background(), &socketmode.Event{
+			Type: socketmode.EventTypeSlashCommand,
+			Data: slack.SlashCommand{
+				Command:     "/deadletter",
+				Text:        text,
+				UserID:      "U0123456",
+				ChannelID:   "C0123456",
+				ResponseURL: testServer.URL + "/response",
+			},
+			Request: &socketmode.Request{EnvelopeID: "dummy"},
+		}, soccketClient)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(responses) == 0 {
+			t.Fatalf("/deadletter %s was not answered", text)
+		}
+		return responses[len(responses)-1]
+	}
+
+	// Nobody can see them until the ACL of the command is configured
+	denied := NewSlashCommandRouter(middleware.NewRouter(socketmode.NewsSocketmodeHandler(soccketClient)), nil)
+	NewDeadLetterController(denied, middleware.DefaultRedactionRules())
+	if got := command(denied, "list"); !strings.Contains(got, "you are not allowed to use `/deadletter` here") {
+		t.Errorf("the command should be denied without ACL: %s", got)
+	}
+
+	if got := command(router, "list"); !strings.Contains(got, id) || !strings.Contains(got, "internal_error") {
+		t.Errorf("the failed event is not listed: %s", got)
+	}
+
+	got := command(router, "inspect "+id)
+	if !strings.Contains(got, "/rocket") || strings.Contains(got, "jane@example.com") {
+		t.Errorf("the envelope should be shown without the email: %s", got)
+	}
+
+	if got := command(router, "replay "+id); !strings.Contains(got, "failed again") {
+		t.Errorf("the replay should fail: %s", got)
+	}
+
+	failure = nil
+	if got := command(router, "replay "+id); !strings.Contains(got, "was handled this time") {
+		t.Errorf("the replay should succeed: %s", got)
+	}
+	if letters, _ := eventHandler.DeadLetters.List(); len(letters) != 0 {
+		t.Errorf("the replayed event is still dead lettered: %+v", letters)
+	}
+
+	// Everything is purged
+	failure = errors.New("internal_error")
+	failing(evt, soccketClient)
+	failing(evt, soccketClient)
+	if got := command(router, "purge"); !strings.Contains(got, "2 failed event(s) purged") {
+		t.Errorf("the failed events should be purged: %s", got)
+	}
+
+	if got := command(router, "inspect unknown"); !strings.Contains(got, "no dead letter with this ID") {
+		t.Errorf("an unknown ID should be explained: %s", got)
+	}
+}
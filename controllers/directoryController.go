@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// We create a sctucture to let us use dependency injection
+type DirectoryController struct {
+	EventHandler *middleware.Router
+	Directory    *services.Directory
+}
+
+func NewDirectoryController(eventhandler *middleware.Router, directory *services.Directory) DirectoryController {
+	c := DirectoryController{
+		EventHandler: eventhandler,
+		Directory:    directory,
+	}
+
+	// A user profile was updated
+	c.EventHandler.HandleEventsAPI(
+		slackevents.EventAPIType("user_change"),
+		c.EventHandler.WithContext(c.refreshUser, 0),
+		middleware.AutoAck,
+	)
+
+	// A channel was renamed
+	c.EventHandler.HandleEventsAPI(
+		slackevents.EventAPIType("channel_rename"),
+		c.EventHandler.WithContext(c.invalidateChannel, 0),
+		middleware.AutoAck,
+	)
+
+	return c
+
+}
+
+func (c *DirectoryController) refreshUser(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into slack.UserChangeEvent
+	evt_api, _ := evt.Data.(slackevents.EventsAPIEvent)
+	evt_user_change, ok := evt_api.InnerEvent.Data.(*slack.UserChangeEvent)
+
+	if ok != true {
+		return errors.New("converting event to slack.UserChangeEvent")
+	}
+
+	// The event contains the updated profile
+	c.Directory.SetUser(evt_user_change.User)
+
+	return nil
+}
+
+func (c *DirectoryController) invalidateChannel(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into slack.ChannelRenameEvent
+	evt_api, _ := evt.Data.(slackevents.EventsAPIEvent)
+	evt_channel_rename, ok := evt_api.InnerEvent.Data.(*slack.ChannelRenameEvent)
+
+	if ok != true {
+		return errors.New("converting event to slack.ChannelRenameEvent")
+	}
+
+	c.Directory.InvalidateChannel(evt_channel_rename.Channel.ID)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestDirectoryController_refreshUser(t *testing.T) {
+
+	testServer, api := setup_slacktest()
+	defer testServer.Stop()
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	type args struct {
+		evt *socketmode.Event
+		clt *socketmode.Client
+	}
+	tests := []struct {
+		name string
+		c    *DirectoryController
+		args args
+		want string
+	}{
+		{
+			name: "Cached user is updated when the profile changes",
+			c:    &DirectoryController{Directory: services.NewDirectory(api, time.Minute)},
+			args: args{
+				evt: &socketmode.Event{
+					Type: socketmode.EventTypeEventsAPI,
+					Data: slackevents.EventsAPIEvent{
+						Type: slackevents.CallbackEvent,
+						InnerEvent: slackevents.EventsAPIInnerEvent{
+							Type: "user_change",
+							Data: &slack.UserChangeEvent{
+								User: slack.User{ID: "U0123456", Name: "renamed"},
+							},
+						},
+					},
+					Request: &socketmode.Request{
+						EnvelopeID: "dummy",
+					},
+				},
+				clt: soccketClient,
+			},
+			want: "renamed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			tt.c.refreshUser(context.Background(), tt.args.evt, tt.args.clt)
+
+			// Then -> the new name is served from the cache
+			if got := tt.c.Directory.UserName("U0123456"); got != tt.want {
+				t.Errorf("UserName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,14 @@
 package controllers
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
 	"xnok/slack-go-demo/views"
 
 	"github.com/slack-go/slack"
@@ -9,96 +16,335 @@ import (
 	"github.com/slack-go/slack/socketmode"
 )
 
+// errInvalidScheduledMessage is the error of chat.deleteScheduledMessage once the message was sent
+const errInvalidScheduledMessage = "invalid_scheduled_message_id"
+
 // We create a sctucture to let us use dependency injection
 type GreetingController struct {
-	EventHandler *socketmode.SocketmodeHandler
+	EventHandler *middleware.Router
+	Onboarding   views.OnboardingSchedule
+	Buddies      *services.BuddyService
+	ReplyMode    ReplyMode
+	Directory    *services.Directory
+	Policy       services.GreetingPolicy
 }
 
-func NewGreetingController(eventhandler *socketmode.SocketmodeHandler) GreetingController {
+func NewGreetingController(eventhandler *middleware.Router, directory *services.Directory, policy services.GreetingPolicy, onboarding views.OnboardingSchedule, buddies *services.BuddyService, replyMode ReplyMode) GreetingController {
 	c := GreetingController{
 		EventHandler: eventhandler,
+		Onboarding:   onboarding,
+		Buddies:      buddies,
+		ReplyMode:    replyMode,
+		Directory:    directory,
+		Policy:       policy,
 	}
 
 	// App Home (2)
 	c.EventHandler.HandleEventsAPI(
 		slackevents.AppMention,
-		c.reactToMention,
+		c.EventHandler.WithContext(handleMention(c.ReplyMode, c.reactToMention), 0),
+		middleware.AutoAck,
 	)
 
 	// App Home (2)
 	c.EventHandler.HandleEventsAPI(
 		slackevents.MemberJoinedChannel,
-		c.postGreetingMessage,
+		c.EventHandler.WithContext(c.postGreetingMessage, 0),
+		middleware.AutoAck,
+	)
+
+	// New member in the workspace
+	c.EventHandler.HandleEventsAPI(
+		slackevents.EventAPIType("team_join"),
+		c.EventHandler.WithContext(c.startOnboarding, 0),
+		middleware.AutoAck,
+	)
+
+	// The new member does not want onboarding messages anymore
+	c.EventHandler.HandleInteractionBlockAction(
+		views.OnboardingOptOutActionID,
+		c.EventHandler.WithContext(c.stopOnboarding, 0),
+		middleware.AutoAck,
 	)
 
 	return c
 
 }
 
-func (c *GreetingController) postGreetingMessage(evt *socketmode.Event, clt *socketmode.Client) {
+func (c *GreetingController) postGreetingMessage(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
 	// we need to cast our socketmode.Event into slackevents.AppHomeOpenedEvent
 	evt_api, _ := evt.Data.(slackevents.EventsAPIEvent)
 	evt_member_join, ok := evt_api.InnerEvent.Data.(*slackevents.MemberJoinedChannelEvent)
 
-	clt.Ack(*evt.Request)
-
 	if ok != true {
-		log.Printf("ERROR converting event to slackevents.AppMentionEvent: %v", ok)
+		return errors.New("converting event to slackevents.MemberJoinedChannelEvent")
 	}
 
-	userInfo, err := clt.GetApiClient().GetUserInfo(evt_member_join.User)
-
+	// the policy decides without the profile when it cannot be found
+	userInfo, err := c.Directory.GetUserInfo(evt_member_join.User)
 	if err != nil {
-		log.Printf("ERROR unable to retrive user info: %v", err)
+		middleware.Logger(ctx).Printf("ERROR unable to retrive user info: %v", err)
+	}
+
+	// Bots, guests and external users are not greeted the same way
+	decision := c.Policy.Evaluate(evt_member_join.Channel, evt_api.TeamID, userInfo)
+	if decision.Skip {
+		return nil
 	}
 
 	// create the view using block-kit
-	blocks := views.GreetingMessage(userInfo.Name)
+	// the directory falls back on a mention if the user cannot be found
+	blocks := views.GreetingMessageFromTemplate(decision.Template, c.Directory.UserName(evt_member_join.User))
 
 	// Post greeting message (3)
-	// We get the Api client from `clt`
-	_, err = clt.GetApiClient().PostEphemeral(
-		evt_member_join.Channel,
-		evt_member_join.User,
+	// We get the Api client from `clt`, the greeting is not posted again when the introduction is retried
+	_, err = middleware.Step(ctx, "greeting", func() (interface{}, error) {
+		return clt.GetApiClient().PostEphemeral(
+			evt_member_join.Channel,
+			evt_member_join.User,
+			slack.MsgOptionBlocks(blocks...),
+		)
+	})
+
+	//Handle errors
+	if err != nil {
+		return fmt.Errorf("postGreetingMessage: %w", err)
+	}
+
+	// Pair the new member with a buddy when the channel has a pool
+	if c.Buddies == nil || userInfo == nil {
+		return nil
+	}
+
+	return c.introduceBuddy(ctx, evt_member_join.Channel, userInfo, clt)
+}
+
+func (c *GreetingController) introduceBuddy(ctx context.Context, channel string, mentee *slack.User, clt *socketmode.Client) error {
+	// The buddy is picked once, a retried handler introduces the same buddy
+	picked, _ := middleware.Step(ctx, "pick the buddy", func() (interface{}, error) {
+		pairing, ok := c.Buddies.Pick(channel, mentee, c.Directory)
+		if !ok {
+			return nil, nil
+		}
+
+		return pairing, nil
+	})
+
+	pairing, ok := picked.(services.Pairing)
+	if !ok {
+		return nil
+	}
+
+	// Open a group DM between the buddy, the mentee and the App
+	conversation, err := middleware.Step(ctx, "open the group DM", func() (interface{}, error) {
+		conversation, _, _, err := clt.GetApiClient().OpenConversation(&slack.OpenConversationParameters{
+			Users: []string{pairing.Buddy, pairing.Mentee},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to open conversation: %w", err)
+		}
+
+		return conversation.ID, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// create the view using block-kit
+	blocks := views.BuddyIntroductionMessage(pairing.Buddy, pairing.Mentee, pairing.Channel)
+
+	_, _, err = clt.GetApiClient().PostMessage(
+		conversation.(string),
 		slack.MsgOptionBlocks(blocks...),
 	)
 
 	//Handle errors
 	if err != nil {
-		log.Printf("ERROR postGreetingMessage: %v", err)
+		return fmt.Errorf("introduceBuddy: %w", err)
 	}
+
+	// The pairing is recorded only once the buddy was introduced
+	c.Buddies.Commit(pairing)
+
+	return nil
 }
 
-func (c *GreetingController) reactToMention(evt *socketmode.Event, clt *socketmode.Client) {
-	// we need to cast our socketmode.Event into slackevents.AppMentionEvent
-	evt_api, _ := evt.Data.(slackevents.EventsAPIEvent)
-	evt_app_mention, ok := evt_api.InnerEvent.Data.(*slackevents.AppMentionEvent)
+func (c *GreetingController) reactToMention(ctx context.Context, mention *slackevents.AppMentionEvent, reply MentionReply, clt *socketmode.Client) error {
+	// create the view using block-kit
+	// the directory falls back on a mention if the user cannot be found
+	blocks := views.GreetingMessage(c.Directory.UserName(mention.User))
 
-	clt.Ack(*evt.Request)
+	// Post greeting message (3) according to the reply mode
+	err := reply.Reply(slack.MsgOptionBlocks(blocks...))
+
+	//Handle errors
+	if err != nil {
+		return fmt.Errorf("reactToMention: %w", err)
+	}
+
+	return nil
+}
+
+func (c *GreetingController) startOnboarding(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into slack.TeamJoinEvent
+	evt_api, _ := evt.Data.(slackevents.EventsAPIEvent)
+	evt_team_join, ok := evt_api.InnerEvent.Data.(*slack.TeamJoinEvent)
 
 	if ok != true {
-		log.Printf("ERROR converting event to slackevents.MemberJoinedChannelEvent: %v", ok)
+		return errors.New("converting event to slack.TeamJoinEvent")
+	}
+
+	// bots do not need to be onboarded
+	if evt_team_join.User.IsBot {
+		return nil
+	}
+
+	// Open the DM with the new member
+	// scheduled messages need a conversation ID and not a user ID
+	// the steps that succeeded are not done again when the handler is retried
+	channel, err := middleware.Step(ctx, "open the DM", func() (interface{}, error) {
+		channel, _, _, err := clt.GetApiClient().OpenConversation(&slack.OpenConversationParameters{
+			Users: []string{evt_team_join.User.ID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to open conversation: %w", err)
+		}
+
+		return channel.ID, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	joined, _ := middleware.Step(ctx, "joined", func() (interface{}, error) {
+		return time.Now(), nil
+	})
+
+	// The last messages are scheduled first, so each message carries the IDs of the ones that follow
+	// and its opt out button can delete them
+	steps := append([]views.OnboardingStep{}, c.Onboarding.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Day > steps[j].Day })
+
+	pending := []string{}
+	for i, step := range steps {
+		// create the view using block-kit
+		blocks := views.OnboardingMessage(c.Onboarding, step, evt_team_join.User.Name, pending)
+
+		if step.Day == 0 {
+			_, err = middleware.Step(ctx, fmt.Sprintf("post step %d", i), func() (interface{}, error) {
+				_, _, err := clt.GetApiClient().PostMessage(
+					channel.(string),
+					slack.MsgOptionBlocks(blocks...),
+				)
+				return nil, err
+			})
+		} else {
+			// Slack takes care of delivering the following messages
+			postAt := strconv.FormatInt(joined.(time.Time).Add(time.Duration(step.Day)*24*time.Hour).Unix(), 10)
+
+			_, err = middleware.Step(ctx, fmt.Sprintf("schedule step %d", i), func() (interface{}, error) {
+				_, _, err := clt.GetApiClient().ScheduleMessage(
+					channel.(string),
+					postAt,
+					slack.MsgOptionBlocks(blocks...),
+				)
+				return nil, err
+			})
+
+			if err == nil {
+				var id interface{}
+				id, err = middleware.Step(ctx, fmt.Sprintf("find step %d", i), func() (interface{}, error) {
+					return scheduledMessageID(clt.GetApiClient(), channel.(string), postAt, pending)
+				})
+				if err == nil {
+					pending = append(pending, id.(string))
+				}
+			}
+		}
+
+		//Handle errors
+		if err != nil {
+			return fmt.Errorf("startOnboarding day %d: %w", step.Day, err)
+		}
 	}
 
-	userInfo, err := clt.GetApiClient().GetUserInfo(evt_app_mention.User)
+	return nil
+}
+
+// scheduledMessageID finds the message scheduled in a channel at postAt, other than the known ones
+// slack.Client.ScheduleMessage does not return the scheduled_message_id
+func scheduledMessageID(client *slack.Client, channel string, postAt string, known []string) (string, error) {
+	scheduled, _, err := client.GetScheduledMessages(&slack.GetScheduledMessagesParameters{
+		Channel: channel,
+		Oldest:  postAt,
+		Latest:  postAt,
+	})
 
 	if err != nil {
-		log.Printf("ERROR unable to retrive user info: %v", err)
+		return "", fmt.Errorf("unable to list scheduled messages: %w", err)
+	}
+
+	// two steps can be scheduled on the same day
+	skipped := make(map[string]bool)
+	for _, id := range known {
+		skipped[id] = true
+	}
+
+	for _, msg := range scheduled {
+		if strconv.Itoa(msg.PostAt) == postAt && !skipped[msg.ID] {
+			return msg.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("no message scheduled at %s in %s", postAt, channel)
+}
+
+func (c *GreetingController) stopOnboarding(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into slack.InteractionCallback
+	interaction, ok := evt.Data.(slack.InteractionCallback)
+
+	if ok != true {
+		return errors.New("converting event to slack.InteractionCallback")
+	}
+
+	client := clt.GetApiClient()
+
+	// Remove the onboarding messages scheduled after the one that was clicked, the button carries their IDs
+	for _, action := range interaction.ActionCallback.BlockActions {
+		if action.ActionID != views.OnboardingOptOutActionID {
+			continue
+		}
+
+		for _, id := range views.OnboardingPending(action.Value) {
+			_, err := client.DeleteScheduledMessage(&slack.DeleteScheduledMessageParameters{
+				Channel:            interaction.Container.ChannelID,
+				ScheduledMessageID: id,
+			})
+
+			// The message may already be sent
+			if err != nil && err.Error() != errInvalidScheduledMessage {
+				return fmt.Errorf("unable to delete scheduled message %s: %w", id, err)
+			}
+		}
 	}
 
 	// create the view using block-kit
-	blocks := views.GreetingMessage(userInfo.Name)
+	blocks := views.OnboardingOptOutMessage(interaction.User.Name)
 
-	// Post greeting message (3) in User's App Home
-	// Pass a user's ID as the value of channel to post to that user's App Home
-	// We get the Api client from `clt`
-	_, _, err = clt.GetApiClient().PostMessage(
-		evt_app_mention.User,
+	// Replace the message that contained the button
+	_, _, err := client.PostMessage(
+		interaction.Container.ChannelID,
 		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionResponseURL(interaction.ResponseURL, slack.ResponseTypeInChannel),
+		slack.MsgOptionReplaceOriginal(interaction.ResponseURL),
 	)
 
 	//Handle errors
 	if err != nil {
-		log.Printf("ERROR reactToMention: %v", err)
+		return fmt.Errorf("stopOnboarding: %w", err)
 	}
+
+	return nil
 }
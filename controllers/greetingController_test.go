@@ -1,9 +1,24 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
 
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
 )
@@ -29,7 +44,7 @@ func TestGreetingController_postGreetingMessage(t *testing.T) {
 	}{
 		{
 			name: "Post greeting message when Member join channel",
-			c:    &GreetingController{},
+			c:    &GreetingController{Directory: services.NewDirectory(api, time.Minute)},
 			args: args{
 				evt: &socketmode.Event{
 					Type: socketmode.EventTypeEventsAPI,
@@ -53,7 +68,7 @@ func TestGreetingController_postGreetingMessage(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// When
-			tt.c.postGreetingMessage(tt.args.evt, tt.args.clt)
+			tt.c.postGreetingMessage(context.Background(), tt.args.evt, tt.args.clt)
 
 			// Then -> recieve ephemeral message
 
@@ -82,7 +97,7 @@ func TestGreetingController_reactToMention(t *testing.T) {
 	}{
 		{
 			name: "Post a message in App Home when App is mentionned",
-			c:    &GreetingController{},
+			c:    &GreetingController{Directory: services.NewDirectory(api, time.Minute)},
 			args: args{
 				evt: &socketmode.Event{
 					Type: socketmode.EventTypeEventsAPI,
@@ -105,7 +120,436 @@ func TestGreetingController_reactToMention(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.c.reactToMention(tt.args.evt, tt.args.clt)
+			handleMention(tt.c.ReplyMode, tt.c.reactToMention)(context.Background(), tt.args.evt, tt.args.clt)
 		})
 	}
 }
+
+func TestGreetingController_startOnboarding(t *testing.T) {
+
+	testServer, api := setup_slacktest()
+	defer testServer.Stop()
+
+	// The DM with the new member
+	testServer.Handle("/conversations.open", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"channel":{"id":"D0123456"}}`))
+	})
+
+	// Count the messages scheduled for the following days
+	var mu sync.Mutex
+	var scheduled int32
+	messages := []slack.ScheduledMessage{}
+	blocks := []string{}
+	testServer.Handle("/chat.scheduleMessage", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		postAt, _ := strconv.Atoi(r.FormValue("post_at"))
+		id := fmt.Sprintf("Q%d", atomic.AddInt32(&scheduled, 1))
+
+		mu.Lock()
+		messages = append(messages, slack.ScheduledMessage{ID: id, Channel: "D0123456", PostAt: postAt})
+		blocks = append(blocks, r.FormValue("blocks"))
+		mu.Unlock()
+
+		w.Write([]byte(`{"ok":true,"channel":"D0123456","scheduled_message_id":"` + id + `"}`))
+	})
+
+	// The scheduled messages are listed to find their IDs
+	testServer.Handle("/chat.scheduledMessages.list", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "scheduled_messages": messages})
+	})
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	onboarding, err := views.LoadOnboardingSchedule("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		evt *socketmode.Event
+		clt *socketmode.Client
+	}
+	tests := []struct {
+		name          string
+		c             *GreetingController
+		args          args
+		wantScheduled int32
+	}{
+		{
+			name: "Schedule onboarding messages when Member join the team",
+			c:    &GreetingController{Onboarding: onboarding},
+			args: args{
+				evt: &socketmode.Event{
+					Type: socketmode.EventTypeEventsAPI,
+					Data: slackevents.EventsAPIEvent{
+						Type: slackevents.CallbackEvent,
+						InnerEvent: slackevents.EventsAPIInnerEvent{
+							Type: "team_join",
+							Data: &slack.TeamJoinEvent{
+								User: slack.User{ID: "U0123456", Name: "David"},
+							},
+						},
+					},
+					Request: &socketmode.Request{
+						EnvelopeID: "dummy",
+					},
+				},
+				clt: soccketClient,
+			},
+			wantScheduled: 2,
+		},
+		{
+			name: "Bots are not onboarded",
+			c:    &GreetingController{Onboarding: onboarding},
+			args: args{
+				evt: &socketmode.Event{
+					Type: socketmode.EventTypeEventsAPI,
+					Data: slackevents.EventsAPIEvent{
+						Type: slackevents.CallbackEvent,
+						InnerEvent: slackevents.EventsAPIInnerEvent{
+							Type: "team_join",
+							Data: &slack.TeamJoinEvent{
+								User: slack.User{ID: "B0123456", Name: "robot", IsBot: true},
+							},
+						},
+					},
+					Request: &socketmode.Request{
+						EnvelopeID: "dummy",
+					},
+				},
+				clt: soccketClient,
+			},
+			wantScheduled: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&scheduled, 0)
+			mu.Lock()
+			messages, blocks = nil, nil
+			mu.Unlock()
+
+			// When
+			if err := tt.c.startOnboarding(context.Background(), tt.args.evt, tt.args.clt); err != nil {
+				t.Fatal(err)
+			}
+
+			// Then -> day 1 and day 7 are scheduled
+			if got := atomic.LoadInt32(&scheduled); got != tt.wantScheduled {
+				t.Errorf("scheduled messages = %v, want %v", got, tt.wantScheduled)
+			}
+
+			// the day 7 first so the opt out button of day 1 can delete it
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.wantScheduled > 0 && !strings.Contains(blocks[1], `"value":"onboarding_opt_out,Q1"`) {
+				t.Errorf("the day 1 does not carry the day 7: %s", blocks[1])
+			}
+		})
+	}
+}
+
+func TestGreetingController_introduceBuddy(t *testing.T) {
+
+	testServer, api := setup_slacktest()
+	defer testServer.Stop()
+
+	// Record who is invited in the group DM
+	var invited string
+	testServer.Handle("/conversations.open", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		invited = r.FormValue("users")
+		w.Write([]byte(`{"ok":true,"channel":{"id":"G0123456"}}`))
+	})
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	buddies := services.NewBuddyService(services.BuddyConfig{
+		Pools: []services.BuddyPool{
+			{Channel: "C0123456", Strategy: services.BuddyRoundRobin, Buddies: []string{"U0000001"}},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		channel     string
+		mentee      *slack.User
+		wantInvited string
+	}{
+		{
+			name:        "Buddy and mentee are introduced",
+			channel:     "C0123456",
+			mentee:      &slack.User{ID: "U0000002"},
+			wantInvited: "U0000001,U0000002",
+		},
+		{
+			name:        "Channel without buddy pool",
+			channel:     "C9999999",
+			mentee:      &slack.User{ID: "U0000003"},
+			wantInvited: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invited = ""
+			c := &GreetingController{Buddies: buddies, Directory: services.NewDirectory(api, time.Minute)}
+
+			// When
+			c.introduceBuddy(context.Background(), tt.channel, tt.mentee, soccketClient)
+
+			// Then -> a group DM is opened
+			if invited != tt.wantInvited {
+				t.Errorf("invited = %v, want %v", invited, tt.wantInvited)
+			}
+		})
+	}
+}
+
+func TestGreetingController_introduceBuddy_Failure(t *testing.T) {
+
+	// The introduction cannot be posted
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conversations.open", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"channel":{"id":"G0123456"}}`))
+	})
+	mux.HandleFunc("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":false,"error":"internal_error"}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	buddies := services.NewBuddyService(services.BuddyConfig{
+		Pools: []services.BuddyPool{
+			{Channel: "C0123456", Strategy: services.BuddyRoundRobin, Buddies: []string{"U0000001"}},
+		},
+	})
+	c := &GreetingController{Buddies: buddies, Directory: services.NewDirectory(api, time.Minute)}
+
+	// When
+	if err := c.introduceBuddy(context.Background(), "C0123456", &slack.User{ID: "U0000002"}, soccketClient); err == nil {
+		t.Fatal("introduceBuddy() should fail when the introduction is not posted")
+	}
+
+	// Then -> the buddy has no mentee, the member can still be paired later
+	if mentees := buddies.Mentees("U0000001"); len(mentees) != 0 {
+		t.Errorf("Mentees() = %v, want none", mentees)
+	}
+	if _, ok := buddies.Pick("C0123456", &slack.User{ID: "U0000002"}, c.Directory); !ok {
+		t.Error("Pick() no buddy for U0000002")
+	}
+}
+
+func TestGreetingController_postGreetingMessage_Policy(t *testing.T) {
+
+	testServer, api := setup_slacktest()
+	defer testServer.Stop()
+
+	// Count the greetings
+	var greeted int32
+	testServer.Handle("/chat.postEphemeral", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&greeted, 1)
+		w.Write([]byte(`{"ok":true,"message_ts":"1.1"}`))
+	})
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	// Profiles are served from the directory cache
+	directory := services.NewDirectory(api, time.Minute)
+	directory.SetUser(slack.User{ID: "U0000001", Name: "david", TeamID: "T0000001"})
+	directory.SetUser(slack.User{ID: "B0000001", Name: "robot", TeamID: "T0000001", IsBot: true})
+
+	tests := []struct {
+		name        string
+		user        string
+		wantGreeted int32
+	}{
+		{name: "Members are greeted", user: "U0000001", wantGreeted: 1},
+		{name: "Bots are not greeted", user: "B0000001", wantGreeted: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&greeted, 0)
+			c := &GreetingController{Directory: directory, Policy: services.DefaultGreetingPolicy()}
+
+			// When
+			c.postGreetingMessage(context.Background(), &socketmode.Event{
+				Type: socketmode.EventTypeEventsAPI,
+				Data: slackevents.EventsAPIEvent{
+					Type:   slackevents.CallbackEvent,
+					TeamID: "T0000001",
+					InnerEvent: slackevents.EventsAPIInnerEvent{
+						Type: string(slackevents.MemberJoinedChannel),
+						Data: &slackevents.MemberJoinedChannelEvent{
+							User:    tt.user,
+							Channel: "C0123456",
+						},
+					},
+				},
+				Request: &socketmode.Request{
+					EnvelopeID: "dummy",
+				},
+			}, soccketClient)
+
+			// Then
+			if got := atomic.LoadInt32(&greeted); got != tt.wantGreeted {
+				t.Errorf("greetings = %v, want %v", got, tt.wantGreeted)
+			}
+		})
+	}
+}
+
+func TestGreetingController_stopOnboarding(t *testing.T) {
+
+	// Record the deleted messages, the day 1 is already sent
+	var mu sync.Mutex
+	deleted := []string{}
+	replaced := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chat.deleteScheduledMessage", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		deleted = append(deleted, r.FormValue("scheduled_message_id"))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("scheduled_message_id") == "Q1" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_scheduled_message_id"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		replaced = true
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	soccketClient := socketmode.New(slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/")))
+
+	c := &GreetingController{}
+
+	// When
+	err := c.stopOnboarding(context.Background(), &socketmode.Event{
+		Type: socketmode.EventTypeInteractive,
+		Data: slack.InteractionCallback{
+			Type:        slack.InteractionTypeBlockActions,
+			User:        slack.User{ID: "U0123456", Name: "David"},
+			Container:   slack.Container{ChannelID: "D0123456"},
+			ResponseURL: testServer.URL + "/response",
+			ActionCallback: slack.ActionCallbacks{
+				BlockActions: []*slack.BlockAction{
+					{ActionID: views.OnboardingOptOutActionID, Value: "onboarding_opt_out,Q1,Q7"},
+				},
+			},
+		},
+		Request: &socketmode.Request{EnvelopeID: "dummy"},
+	}, soccketClient)
+
+	// Then -> only the messages carried by the button are deleted
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := deep.Equal(deleted, []string{"Q1", "Q7"}); diff != nil {
+		t.Error(diff)
+	}
+	if !replaced {
+		t.Error("the message with the button is not replaced")
+	}
+}
+
+func TestGreetingController_startOnboarding_Retry(t *testing.T) {
+
+	// The second message cannot be scheduled the first time
+	var mu sync.Mutex
+	calls := map[string]int{}
+	messages := []slack.ScheduledMessage{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/conversations.open":
+			w.Write([]byte(`{"ok":true,"channel":{"id":"D0123456"}}`))
+		case "/chat.scheduleMessage":
+			if calls[r.URL.Path] == 2 {
+				w.Write([]byte(`{"ok":false,"error":"internal_error"}`))
+				return
+			}
+			postAt, _ := strconv.Atoi(r.FormValue("post_at"))
+			messages = append(messages, slack.ScheduledMessage{ID: fmt.Sprintf("Q%d", len(messages)+1), PostAt: postAt})
+			w.Write([]byte(`{"ok":true,"channel":"D0123456"}`))
+		case "/chat.scheduledMessages.list":
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "scheduled_messages": messages})
+		default:
+			w.Write([]byte(`{"ok":true,"channel":"D0123456","ts":"1.1"}`))
+		}
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	soccketClient := socketmode.New(slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/")))
+
+	onboarding, err := views.LoadOnboardingSchedule("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := middleware.NewRouter(socketmode.NewsSocketmodeHandler(soccketClient))
+	router.Retry = middleware.RetryPolicy{Attempts: 3, Multiplier: 1}
+	c := &GreetingController{EventHandler: router, Onboarding: onboarding}
+
+	// When
+	router.WithContext(c.startOnboarding, 0)(&socketmode.Event{
+		Type: socketmode.EventTypeEventsAPI,
+		Data: slackevents.EventsAPIEvent{
+			Type: slackevents.CallbackEvent,
+			InnerEvent: slackevents.EventsAPIInnerEvent{
+				Type: "team_join",
+				Data: &slack.TeamJoinEvent{User: slack.User{ID: "U0123456", Name: "David"}},
+			},
+		},
+		Request: &socketmode.Request{EnvelopeID: "dummy"},
+	}, soccketClient)
+
+	// Then -> the retry only does what failed
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"/conversations.open": 1, "/chat.scheduleMessage": 3, "/chat.scheduledMessages.list": 2, "/chat.postMessage": 1}
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Error(diff)
+	}
+	if len(messages) != 2 {
+		t.Errorf("%d messages scheduled, want 2", len(messages))
+	}
+}
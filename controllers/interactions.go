@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"xnok/slack-go-demo/middleware"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// viewSubmission only lets the modal with the given callback ID through
+// the SocketmodeHandler dispatches every view submission to every handler
+func viewSubmission(callbackID string) middleware.Middleware {
+	return func(next middleware.Handler) middleware.Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			interaction, ok := evt.Data.(slack.InteractionCallback)
+			if !ok || interaction.View.CallbackID != callbackID {
+				return
+			}
+
+			next(evt, clt)
+		}
+	}
+}
+
+// blockSuggestion only lets the options load of the given action ID through
+func blockSuggestion(actionID string) middleware.Middleware {
+	return func(next middleware.Handler) middleware.Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			interaction, ok := evt.Data.(slack.InteractionCallback)
+			if !ok || interaction.ActionID != actionID {
+				return
+			}
+
+			next(evt, clt)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/slack-go/slack"
+)
+
+var rocketStatsCommand = commands.Spec{
+	Command: "/rocket stats",
+	Args: []commands.Arg{
+		{Name: "limit", Type: commands.TypeInt, Default: 5, Usage: "number of recent launches"},
+	},
+}
+
+// maxStatsLimit keeps the message under the block limit of slack
+const maxStatsLimit = 20
+
+// homeTabLaunches is the number of recent launches shown in the home tab
+const homeTabLaunches = 5
+
+// leaderboardSize is the number of users and channels ranked
+const leaderboardSize = 5
+
+// ConversationMembersGetter is the part of the slack API we need to check the members of a private channel
+type ConversationMembersGetter interface {
+	GetUsersInConversation(params *slack.GetUsersInConversationParameters) ([]string, string, error)
+}
+
+// launchAudience keeps the launches a user is allowed to see
+// the silent launches are hidden, the ones of a private channel or a DM are only shown to its members
+func launchAudience(user string, directory *services.Directory, members ConversationMembersGetter) services.LaunchFilter {
+	// a channel is checked once per view
+	visible := map[string]bool{}
+
+	return func(r services.LaunchRecord) bool {
+		if r.Silent {
+			return false
+		}
+
+		if _, ok := visible[r.Channel]; !ok {
+			visible[r.Channel] = canSeeChannel(user, r.Channel, directory, members)
+		}
+
+		return visible[r.Channel]
+	}
+}
+
+// canSeeChannel hides the channels that cannot be checked
+func canSeeChannel(user string, channel string, directory *services.Directory, members ConversationMembersGetter) bool {
+	info, err := directory.GetConversationInfo(channel)
+	if err != nil {
+		log.Printf("WARNING unable to read the channel %s, its launches are hidden: %v", channel, err)
+		return false
+	}
+
+	if !info.IsPrivate && !info.IsIM && !info.IsMpIM {
+		return true
+	}
+
+	params := &slack.GetUsersInConversationParameters{ChannelID: channel}
+	for {
+		users, cursor, err := members.GetUsersInConversation(params)
+		if err != nil {
+			log.Printf("WARNING unable to read the members of %s, its launches are hidden: %v", channel, err)
+			return false
+		}
+
+		for _, member := range users {
+			if member == user {
+				return true
+			}
+		}
+
+		if cursor == "" {
+			return false
+		}
+		params.Cursor = cursor
+	}
+}
+
+// launchStats reads the recent launches and the leaderboards of the history kept by filter
+func launchStats(history *services.LaunchHistory, limit int, filter services.LaunchFilter) ([]views.LaunchSummary, []views.LeaderboardEntry, []views.LeaderboardEntry) {
+	recent := []views.LaunchSummary{}
+	for _, r := range history.RecentMatching(limit, filter) {
+		recent = append(recent, views.LaunchSummary{
+			Requester: r.Requester,
+			Approvers: r.Approvers,
+			Rocket:    r.Rocket,
+			Channel:   r.Channel,
+			Countdown: r.Countdown,
+			Outcome:   string(r.Outcome),
+			Timestamp: r.Timestamp,
+		})
+	}
+
+	users, channels := history.LeaderboardsMatching(leaderboardSize, filter)
+
+	return recent, leaderboardView(users), leaderboardView(channels)
+}
+
+func leaderboardView(entries []services.LeaderboardEntry) []views.LeaderboardEntry {
+	view := []views.LeaderboardEntry{}
+	for _, entry := range entries {
+		view = append(view, views.LeaderboardEntry{ID: entry.ID, Launches: entry.Launches})
+	}
+
+	return view
+}
+
+// showLaunchStats answers /rocket stats with the recent launches and the leaderboards
+func (c SlashCommandController) showLaunchStats(req commands.Request) error {
+	command, clt := req.Command, req.Client
+
+	limit := req.Values.Int("limit")
+	if limit < 1 || limit > maxStatsLimit {
+		return &commands.UsageError{
+			Reason: fmt.Sprintf("the limit must be between 1 and %d", maxStatsLimit),
+			Usage:  rocketStatsCommand.Usage(),
+		}
+	}
+
+	// the user only sees the launches of the channels they are in
+	recent, users, channels := launchStats(c.History, limit, launchAudience(command.UserID, c.Directory, clt.GetApiClient()))
+
+	_, _, err := clt.GetApiClient().PostMessage(
+		command.ChannelID,
+		slack.MsgOptionBlocks(views.LaunchHistoryBlocks(recent, users, channels)...),
+		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	return err
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+)
+
+func TestLaunchStats_Audience(t *testing.T) {
+	// C1 is public, G1 is private and U1 is one of its members
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conversations.info", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("channel") == "G1" {
+			w.Write([]byte(`{"ok":true,"channel":{"id":"G1","name":"launch-team","is_private":true}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"channel":{"id":"` + r.FormValue("channel") + `","name":"general"}}`))
+	})
+	mux.HandleFunc("/conversations.members", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("cursor") == "" {
+			w.Write([]byte(`{"ok":true,"members":["U3"],"response_metadata":{"next_cursor":"page2"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"members":["U1"],"response_metadata":{"next_cursor":""}}`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+	directory := services.NewDirectory(api, time.Minute)
+
+	history := services.NewLaunchHistory()
+	history.Record(services.LaunchRecord{Requester: "U1", Channel: "C1", Rocket: "Falcon 9", Outcome: services.OutcomeLaunched})
+	history.Record(services.LaunchRecord{Requester: "U1", Channel: "G1", Rocket: "Starship", Outcome: services.OutcomeLaunched})
+	history.Record(services.LaunchRecord{Requester: "U2", Channel: "C1", Rocket: "Starship", Outcome: services.OutcomeLaunched, Silent: true})
+
+	tests := []struct {
+		name         string
+		user         string
+		wantRockets  []string
+		wantChannels []views.LeaderboardEntry
+	}{
+		{
+			name:         "Member of the private channel",
+			user:         "U1",
+			wantRockets:  []string{"Starship", "Falcon 9"},
+			wantChannels: []views.LeaderboardEntry{{ID: "C1", Launches: 1}, {ID: "G1", Launches: 1}},
+		},
+		{
+			name:         "Other user",
+			user:         "U2",
+			wantRockets:  []string{"Falcon 9"},
+			wantChannels: []views.LeaderboardEntry{{ID: "C1", Launches: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recent, _, channels := launchStats(history, 10, launchAudience(tt.user, directory, api))
+
+			rockets := []string{}
+			for _, launch := range recent {
+				rockets = append(rockets, launch.Rocket)
+			}
+
+			// the silent launch is never shown
+			if diff := deep.Equal(rockets, tt.wantRockets); diff != nil {
+				t.Error(diff)
+			}
+			if diff := deep.Equal(channels, tt.wantChannels); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
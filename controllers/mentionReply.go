@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"xnok/slack-go-demo/middleware"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// ReplyMode defines where the App answers when it is mentioned
+type ReplyMode string
+
+const (
+	// ReplyInThread answers in a thread of the message that mentioned the App
+	ReplyInThread ReplyMode = "thread"
+	// ReplyEphemeral answers in the channel but only the author can see it
+	ReplyEphemeral ReplyMode = "ephemeral"
+	// ReplyDirectMessage answers in the App DM
+	ReplyDirectMessage ReplyMode = "dm"
+)
+
+// ParseReplyMode validates a reply mode from the configuration
+// An empty value defaults to ReplyInThread, the App DM is opt-in
+func ParseReplyMode(mode string) (ReplyMode, error) {
+	switch ReplyMode(mode) {
+	case "":
+		return ReplyInThread, nil
+	case ReplyInThread, ReplyEphemeral, ReplyDirectMessage:
+		return ReplyMode(mode), nil
+	}
+
+	return "", fmt.Errorf("unknown reply mode %q", mode)
+}
+
+// MentionReply posts answers to an app mention according to the reply mode
+type MentionReply struct {
+	Mode    ReplyMode
+	Mention *slackevents.AppMentionEvent
+	Client  *slack.Client
+}
+
+// ThreadTimestamp is the thread the answer belongs to
+// if the mention was already in a thread we keep answering in the same thread
+func (r MentionReply) ThreadTimestamp() string {
+	if r.Mention.ThreadTimeStamp != "" {
+		return r.Mention.ThreadTimeStamp
+	}
+
+	return r.Mention.TimeStamp
+}
+
+// Reply posts a message where the user expects it, in a thread unless another mode is set
+func (r MentionReply) Reply(options ...slack.MsgOption) error {
+	var err error
+
+	switch r.Mode {
+	case ReplyEphemeral:
+		// only the author sees it in the channel, there is no thread to start
+		_, err = r.Client.PostEphemeral(r.Mention.Channel, r.Mention.User, options...)
+	case ReplyDirectMessage:
+		// Pass a user's ID as the value of channel to post to that user's App Home
+		_, _, err = r.Client.PostMessage(r.Mention.User, options...)
+	default:
+		options = append(options, slack.MsgOptionTS(r.ThreadTimestamp()))
+		_, _, err = r.Client.PostMessage(r.Mention.Channel, options...)
+	}
+
+	return err
+}
+
+// MentionHandlerFunc is a handler for app mentions that answers with a MentionReply
+type MentionHandlerFunc func(ctx context.Context, mention *slackevents.AppMentionEvent, reply MentionReply, clt *socketmode.Client) error
+
+// handleMention converts a MentionHandlerFunc into a ContextHandler
+func handleMention(mode ReplyMode, f MentionHandlerFunc) middleware.ContextHandler {
+	return func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		// we need to cast our socketmode.Event into slackevents.AppMentionEvent
+		evt_api, _ := evt.Data.(slackevents.EventsAPIEvent)
+		evt_app_mention, ok := evt_api.InnerEvent.Data.(*slackevents.AppMentionEvent)
+
+		if ok != true {
+			return errors.New("converting event to slackevents.AppMentionEvent")
+		}
+
+		reply := MentionReply{
+			Mode:    mode,
+			Mention: evt_app_mention,
+			Client:  clt.GetApiClient(),
+		}
+
+		return f(ctx, evt_app_mention, reply, clt)
+	}
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestParseReplyMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		want    ReplyMode
+		wantErr bool
+	}{
+		{name: "Default", mode: "", want: ReplyInThread},
+		{name: "Thread", mode: "thread", want: ReplyInThread},
+		{name: "Ephemeral", mode: "ephemeral", want: ReplyEphemeral},
+		{name: "Direct message", mode: "dm", want: ReplyDirectMessage},
+		{name: "Unknown", mode: "channel", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReplyMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReplyMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReplyMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionReply_Reply(t *testing.T) {
+	// Record the API method and the parameters used to answer
+	var method, channel, threadTS string
+	mux := http.NewServeMux()
+	record := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		method = r.URL.Path
+		channel = r.FormValue("channel")
+		threadTS = r.FormValue("thread_ts")
+		w.Write([]byte(`{"ok":true,"channel":"C0123456","ts":"1.1","message_ts":"1.1"}`))
+	}
+	mux.HandleFunc("/chat.postMessage", record)
+	mux.HandleFunc("/chat.postEphemeral", record)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+
+	mention := &slackevents.AppMentionEvent{
+		User:      "U0123456",
+		Channel:   "C0123456",
+		TimeStamp: "1620000000.000100",
+	}
+	threaded := &slackevents.AppMentionEvent{
+		User:            "U0123456",
+		Channel:         "C0123456",
+		TimeStamp:       "1620000000.000200",
+		ThreadTimeStamp: "1620000000.000001",
+	}
+
+	tests := []struct {
+		name         string
+		reply        MentionReply
+		wantMethod   string
+		wantChannel  string
+		wantThreadTS string
+	}{
+		{
+			name:         "Reply in a new thread",
+			reply:        MentionReply{Mode: ReplyInThread, Mention: mention, Client: api},
+			wantMethod:   "/chat.postMessage",
+			wantChannel:  "C0123456",
+			wantThreadTS: "1620000000.000100",
+		},
+		{
+			name:         "Reply in the existing thread",
+			reply:        MentionReply{Mode: ReplyInThread, Mention: threaded, Client: api},
+			wantMethod:   "/chat.postMessage",
+			wantChannel:  "C0123456",
+			wantThreadTS: "1620000000.000001",
+		},
+		{
+			name:         "Reply with an ephemeral message",
+			reply:        MentionReply{Mode: ReplyEphemeral, Mention: threaded, Client: api},
+			wantMethod:   "/chat.postEphemeral",
+			wantChannel:  "C0123456",
+			wantThreadTS: "",
+		},
+		{
+			name:         "Reply in the App DM",
+			reply:        MentionReply{Mode: ReplyDirectMessage, Mention: mention, Client: api},
+			wantMethod:   "/chat.postMessage",
+			wantChannel:  "U0123456",
+			wantThreadTS: "",
+		},
+		{
+			name:         "Reply in a thread by default",
+			reply:        MentionReply{Mention: threaded, Client: api},
+			wantMethod:   "/chat.postMessage",
+			wantChannel:  "C0123456",
+			wantThreadTS: "1620000000.000001",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.reply.Reply(slack.MsgOptionText("hello", false)); err != nil {
+				t.Fatalf("Reply() error = %v", err)
+			}
+			if method != tt.wantMethod {
+				t.Errorf("method = %v, want %v", method, tt.wantMethod)
+			}
+			if channel != tt.wantChannel {
+				t.Errorf("channel = %v, want %v", channel, tt.wantChannel)
+			}
+			if threadTS != tt.wantThreadTS {
+				t.Errorf("thread_ts = %v, want %v", threadTS, tt.wantThreadTS)
+			}
+		})
+	}
+}
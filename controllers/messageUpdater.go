@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// errNotInChannel is the error of chat.postMessage in the channels the App did not join
+const errNotInChannel = "not_in_channel"
+
+// MessageUpdater keeps a message up to date for long running interactions
+// response URLs can only be used a few times so the message is posted once
+// and then updated with chat.update using its channel and timestamp
+// Ephemeral messages cannot be updated with chat.update, they use the response URL
+// as well as the messages of the channels the App did not join
+type MessageUpdater struct {
+	Client      *slack.Client
+	Channel     string
+	Timestamp   string
+	ResponseURL string
+	Ephemeral   bool
+	// notInChannel is set once chat.postMessage failed because the App did not join the channel
+	notInChannel bool
+}
+
+// NewMessageUpdater prepares the update of a message not posted yet
+// responseURL is used to remove the message that triggered the interaction
+// or to post and update the message when it is ephemeral
+func NewMessageUpdater(client *slack.Client, channel string, responseURL string, ephemeral bool) *MessageUpdater {
+	return &MessageUpdater{
+		Client:      client,
+		Channel:     channel,
+		ResponseURL: responseURL,
+		Ephemeral:   ephemeral,
+	}
+}
+
+// Update posts the message the first time then updates it
+func (u *MessageUpdater) Update(blocks ...slack.Block) error {
+	if u.Ephemeral {
+		return u.respond(slack.ResponseTypeEphemeral, blocks...)
+	}
+
+	if u.notInChannel {
+		return u.respond(slack.ResponseTypeInChannel, blocks...)
+	}
+
+	if u.Timestamp != "" {
+		_, _, _, err := u.Client.UpdateMessage(u.Channel, u.Timestamp, slack.MsgOptionBlocks(blocks...))
+		return err
+	}
+
+	channel, timestamp, err := u.Client.PostMessage(u.Channel, slack.MsgOptionBlocks(blocks...))
+	if err != nil && err.Error() == errNotInChannel && u.ResponseURL != "" {
+		// The response URL can still post in the channel, it replaces the message that triggered the interaction
+		u.notInChannel = true
+		return u.respond(slack.ResponseTypeInChannel, blocks...)
+	}
+	if err != nil {
+		return err
+	}
+
+	u.Channel = channel
+	u.Timestamp = timestamp
+
+	// The ephemeral message that triggered the interaction is not needed anymore
+	if u.ResponseURL != "" {
+		_, _, err = u.Client.PostMessage(u.Channel, slack.MsgOptionDeleteOriginal(u.ResponseURL))
+	}
+
+	return err
+}
+
+// respond replaces the message that triggered the interaction through the response URL
+func (u *MessageUpdater) respond(responseType string, blocks ...slack.Block) error {
+	_, _, err := u.Client.PostMessage(
+		u.Channel,
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionResponseURL(u.ResponseURL, responseType),
+		slack.MsgOptionReplaceOriginal(u.ResponseURL),
+	)
+	return err
+}
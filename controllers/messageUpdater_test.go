@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+)
+
+func TestMessageUpdater_Update(t *testing.T) {
+	// Record the API methods used to update the message
+	var calls []string
+	mux := http.NewServeMux()
+	record := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		calls = append(calls, r.URL.Path+" "+r.FormValue("ts"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"channel":"C0123456","ts":"1.1"}`))
+	}
+	mux.HandleFunc("/chat.postMessage", record)
+	mux.HandleFunc("/chat.update", record)
+	mux.HandleFunc("/response", record)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+	blocks := []slack.Block{slack.NewDividerBlock()}
+
+	tests := []struct {
+		name      string
+		ephemeral bool
+		want      []string
+	}{
+		{
+			name: "Posted once then updated",
+			want: []string{
+				"/chat.postMessage ",
+				"/response ",
+				"/chat.update 1.1",
+				"/chat.update 1.1",
+			},
+		},
+		{
+			name:      "Ephemeral messages use the response URL",
+			ephemeral: true,
+			want: []string{
+				"/response ",
+				"/response ",
+				"/response ",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			u := NewMessageUpdater(api, "C0123456", testServer.URL+"/response", tt.ephemeral)
+
+			for i := 0; i < 3; i++ {
+				if err := u.Update(blocks...); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+			}
+
+			if diff := deep.Equal(calls, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMessageUpdater_Update_NotInChannel(t *testing.T) {
+	// The App did not join the channel
+	var calls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":false,"error":"not_in_channel"}`))
+	})
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ResponseType    string `json:"response_type"`
+			ReplaceOriginal bool   `json:"replace_original"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		calls = append(calls, fmt.Sprintf("%s %s replace:%v", r.URL.Path, msg.ResponseType, msg.ReplaceOriginal))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+	u := NewMessageUpdater(api, "C0123456", testServer.URL+"/response", false)
+
+	for i := 0; i < 3; i++ {
+		if err := u.Update(slack.NewDividerBlock()); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+
+	// The message is posted and updated in the channel through the response URL
+	want := []string{
+		"/chat.postMessage",
+		"/response in_channel replace:true",
+		"/response in_channel replace:true",
+		"/response in_channel replace:true",
+	}
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Error(diff)
+	}
+}
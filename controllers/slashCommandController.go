@@ -1,101 +1,529 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
-	"time"
+	"strings"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
 	"xnok/slack-go-demo/views"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/socketmode"
 )
 
+// rocketFlags are the launch parameters shared by /rocket and its scheduled versions
+var rocketFlags = []commands.Flag{
+	{Name: "rocket", Type: commands.TypeString, Default: "", Usage: "name of a rocket of the catalog"},
+	{Name: "silent", Type: commands.TypeBool, Default: false, Usage: "only you can see the launch"},
+}
+
+// countArg defaults to the count down of the rocket
+var countArg = commands.Arg{Name: "count", Type: commands.TypeInt, Usage: "count down in seconds"}
+
+// rocketCommand describes the arguments of /rocket
+// e.g. /rocket 10 --rocket "Starship" --silent
+var rocketCommand = commands.Spec{
+	Command: "/rocket",
+	Args:    []commands.Arg{countArg},
+	Flags:   rocketFlags,
+}
+
+// maxCountDown keeps the count down reasonable
+const maxCountDown = services.MaxCountDown
+
 // We create a sctucture to let us use dependency injection
 type SlashCommandController struct {
-	EventHandler *socketmode.SocketmodeHandler
+	EventHandler *middleware.Router
+	Commands     *SlashCommandRouter
+	Decisions    *services.DecisionLog
+	Countdowns   *services.CountdownService
+	Approvals    *services.LaunchApprovals
+	Scheduler    *services.LaunchScheduler
+	Directory    *services.Directory
+	History      *services.LaunchHistory
+	Catalog      services.RocketCatalog
 }
 
-func NewSlashCommandController(eventhandler *socketmode.SocketmodeHandler) SlashCommandController {
+func NewSlashCommandController(router *SlashCommandRouter, decisions *services.DecisionLog, countdowns *services.CountdownService, approvals *services.LaunchApprovals, scheduler *services.LaunchScheduler, directory *services.Directory, history *services.LaunchHistory, catalog services.RocketCatalog) SlashCommandController {
 	// we need to cast our socketmode.Event into a SlashCommand
 	c := SlashCommandController{
-		EventHandler: eventhandler,
+		EventHandler: router.EventHandler,
+		Commands:     router,
+		Decisions:    decisions,
+		Countdowns:   countdowns,
+		Approvals:    approvals,
+		Scheduler:    scheduler,
+		Directory:    directory,
+		History:      history,
+		Catalog:      catalog,
 	}
 
-	// Register callback for the command /rocket
-	c.EventHandler.HandleSlashCommand(
-		"/rocket",
-		c.launchRocketAnnoncement,
-	)
+	// Register the command /rocket and its subcommands
+	c.Commands.Register(c.rocketCommands())
 
 	// The rocket launch is approved
 	c.EventHandler.HandleInteractionBlockAction(
 		views.RocketAnnoncementActionID,
-		c.launchRocket,
+		c.EventHandler.WithContext(c.launchRocket, 0),
+		middleware.AutoAck,
+	)
+
+	// The rocket launch is denied
+	c.EventHandler.HandleInteractionBlockAction(
+		views.RocketDenyActionID,
+		c.EventHandler.WithContext(c.denyRocketLaunch, 0),
+		middleware.AutoAck,
+	)
+
+	// Another rocket is picked in the announcement
+	c.EventHandler.HandleInteractionBlockAction(
+		views.RocketSelectActionID,
+		c.selectRocket,
+		middleware.AutoAck,
+	)
+
+	// The rockets of the catalog are loaded by the select
+	c.EventHandler.HandleInteraction(
+		slack.InteractionTypeBlockSuggestion,
+		c.loadRocketOptions,
+		blockSuggestion(views.RocketSelectActionID),
+	)
+
+	// The count down is aborted before the launch
+	c.EventHandler.HandleInteractionBlockAction(
+		views.RocketAbortActionID,
+		c.EventHandler.WithContext(c.abortRocketLaunch, 0),
+		middleware.AutoAck,
+	)
+
+	// The reason of the denial is submitted
+	c.EventHandler.HandleInteraction(
+		slack.InteractionTypeViewSubmission,
+		c.EventHandler.WithContext(c.saveDenyReason, 0),
+		viewSubmission(views.RocketDenyReasonCallbackID),
+		middleware.AutoAck,
 	)
 
 	return c
 
 }
 
-func (c SlashCommandController) launchRocketAnnoncement(evt *socketmode.Event, clt *socketmode.Client) {
-	// we need to cast our socketmode.Event into a Slash Command
-	command, ok := evt.Data.(slack.SlashCommand)
-
-	if ok != true {
-		log.Printf("ERROR converting event to Slash Command: %v", ok)
+// rocketCommands defines /rocket and its subcommands
+func (c SlashCommandController) rocketCommands() *commands.Command {
+	return &commands.Command{
+		Spec:        rocketCommand,
+		Description: "Announce a rocket launch, it starts once approved",
+		Run:         c.announceRocketLaunch,
+		Subcommands: []*commands.Command{
+			{Spec: rocketAtCommand, Description: "Announce a launch at a time of your day", Run: c.scheduleRocketLaunch(rocketAtCommand)},
+			{Spec: rocketInCommand, Description: "Announce a launch after a delay", Run: c.scheduleRocketLaunch(rocketInCommand)},
+			{Spec: rocketListCommand, Description: "List the launches scheduled in this channel", Run: c.listScheduledLaunches},
+			{Spec: rocketCancelCommand, Description: "Cancel one of your scheduled launches", Run: c.cancelScheduledLaunch},
+			{Spec: rocketStatsCommand, Description: "Show the recent launches and the leaderboards", Run: c.showLaunchStats},
+		},
 	}
+}
+
+// announceRocketLaunch posts the announcement right away
+func (c SlashCommandController) announceRocketLaunch(req commands.Request) error {
+	command, clt := req.Command, req.Client
 
-	// Make sure to respond to the server to avoid an error
-	clt.Ack(*evt.Request)
+	// pick the rocket of the catalog
+	launch, err := launchFromValues(req.Values, rocketCommand, c.Catalog)
+	if err != nil {
+		return err
+	}
 
-	// parse the command line
-	count := 3
+	// The launch waits for its approvals, the same request is announced when the command is retried
+	opened, _ := middleware.Step(req.Context, "open the launch request", func() (interface{}, error) {
+		return c.Approvals.Open(command.UserID, command.ChannelID), nil
+	})
+	request := opened.(services.LaunchRequest)
+	launch.RequestID = request.ID
 
 	// create the view using block-kit
-	blocks := views.LaunchRocketAnnoncement(count)
+	blocks := views.LaunchRocketAnnoncement(launch, c.rocket(launch.Rocket).Description, c.approval(request))
 
-	client := clt.GetApiClient()
-
-	// Post ephemeral message
-	_, _, err := client.PostMessage(
+	// Post ephemeral message, unless other users have to approve the launch
+	_, _, err = clt.GetApiClient().PostMessage(
 		command.ChannelID,
 		slack.MsgOptionBlocks(blocks...),
-		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+		slack.MsgOptionResponseURL(command.ResponseURL, c.announcementResponseType()),
 	)
 
-	// Handle errors
-	if err != nil {
-		log.Printf("ERROR while sending message for /rocket: %v", err)
-	}
+	return err
+}
 
+// approval is the result of an approval click
+type approval struct {
+	request services.LaunchRequest
+	quorum  bool
 }
 
-func (c SlashCommandController) launchRocket(evt *socketmode.Event, clt *socketmode.Client) {
+func (c SlashCommandController) launchRocket(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
 	// we need to cast our socketmode.Event into a Slash Command
 	interaction := evt.Data.(slack.InteractionCallback)
 
-	// Make sure to respond to the server to avoid an error
-	clt.Ack(*evt.Request)
-
-	// parse the command line
-	count := 3
-
-	for i := count; i >= 0; i-- {
-		// create the view using block-kit
-		blocks := views.LaunchRocket(i)
+	// The launch parameters are carried by the approval button
+	launch := c.launchFromAction(interaction, views.RocketAnnoncementActionID)
 
-		time.Sleep(1000 * time.Millisecond)
+	// A retried handler does not approve twice
+	approved, err := middleware.Step(ctx, "approve the launch request", func() (interface{}, error) {
+		request, quorum, err := c.Approvals.Approve(launch.RequestID, interaction.User.ID, interaction.Container.ChannelID, launch.Rocket)
+		return approval{request: request, quorum: quorum}, err
+	})
+	if err != nil {
+		return c.refuseApproval(interaction, err, clt)
+	}
+	request, quorum := approved.(approval).request, approved.(approval).quorum
 
-		_, _, err := clt.GetApiClient().PostMessage(
+	// Show who approved so far and wait for the others
+	if !quorum {
+		_, _, err = clt.GetApiClient().PostMessage(
 			interaction.Container.ChannelID,
-			slack.MsgOptionBlocks(blocks...),
-			slack.MsgOptionResponseURL(interaction.ResponseURL, slack.ResponseTypeInChannel),
+			slack.MsgOptionBlocks(views.LaunchRocketAnnoncement(launch, c.rocket(launch.Rocket).Description, c.approval(request))...),
+			slack.MsgOptionResponseURL(interaction.ResponseURL, c.announcementResponseType()),
 			slack.MsgOptionReplaceOriginal(interaction.ResponseURL),
 		)
 
+		if err != nil {
+			return fmt.Errorf("while updating the approvals of /rocket: %w", err)
+		}
+		return nil
+	}
+
+	c.Decisions.Record(services.LaunchDecision{
+		Type:    services.LaunchApproved,
+		Actor:   interaction.User.ID,
+		Channel: interaction.Container.ChannelID,
+		Rocket:  launch.Rocket,
+	})
+
+	// The launch is in the history until the end of the count down
+	record := c.History.Record(services.LaunchRecord{
+		Requester: request.Requester,
+		Approvers: request.Approvers,
+		Rocket:    launch.Rocket,
+		Channel:   interaction.Container.ChannelID,
+		Countdown: launch.Count,
+		Outcome:   services.OutcomeInFlight,
+		Silent:    launch.Silent,
+	})
+	launch.RecordID = record.ID
+
+	// A silent launch stays visible only to the requester
+	// otherwise the count down is posted in the channel and updated with chat.update
+	updater := NewMessageUpdater(clt.GetApiClient(), interaction.Container.ChannelID, interaction.ResponseURL, launch.Silent)
+
+	// The count down runs in the background so it can be aborted
+	// every tick replaces the message with the next frame
+	rocket := c.rocket(launch.Rocket)
+	c.Countdowns.Start(launch.Count, func(id string, remaining int) {
+		launch.CountdownID = id
+
+		err := updater.Update(views.LaunchRocket(remaining, rocket.Frame(remaining), launch)...)
+
 		// Handle errors
 		if err != nil {
 			log.Printf("ERROR while sending message for /rocket: %v", err)
 		}
+
+		if remaining == 0 {
+			c.History.SetOutcome(record.ID, services.OutcomeLaunched)
+		}
+	})
+
+	return nil
+}
+
+// refuseApproval explains to the user why the approval was not counted
+// the approvals that could not be checked are failures of the handler
+func (c SlashCommandController) refuseApproval(interaction slack.InteractionCallback, refusal error, clt *socketmode.Client) error {
+	reason := refusal.Error()
+
+	var failure error
+	switch refusal {
+	case services.ErrLaunchRequestNotFound, services.ErrLaunchRequestExpired,
+		services.ErrRequesterCannotApprove, services.ErrNotAnApprover, services.ErrAlreadyApproved,
+		services.ErrWrongChannel:
+	default:
+		failure = fmt.Errorf("unable to check the approval of /rocket: %w", refusal)
+		reason = "your approval could not be checked, please try again"
+	}
+
+	// Only the user who clicked sees the explanation
+	_, _, err := clt.GetApiClient().PostMessage(
+		interaction.Container.ChannelID,
+		slack.MsgOptionBlocks(views.RocketApprovalError(reason)...),
+		slack.MsgOptionResponseURL(interaction.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	if err != nil && failure == nil {
+		failure = fmt.Errorf("while refusing an approval of /rocket: %w", err)
+	}
+
+	return failure
+}
+
+// approval describes the progress of a launch request for the announcement
+func (c SlashCommandController) approval(request services.LaunchRequest) views.RocketApproval {
+	return views.RocketApproval{
+		Requester: request.Requester,
+		Approvers: request.Approvers,
+		Required:  c.Approvals.Policy().Approvers,
+	}
+}
+
+// announcementResponseType shares the announcement when other users have to approve it
+func (c SlashCommandController) announcementResponseType() string {
+	if c.Approvals.Policy().Shared() {
+		return slack.ResponseTypeInChannel
+	}
+
+	return slack.ResponseTypeEphemeral
+}
+
+func (c SlashCommandController) abortRocketLaunch(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into slack.InteractionCallback
+	interaction := evt.Data.(slack.InteractionCallback)
+
+	// The count down is carried by the abort button
+	launch := c.launchFromAction(interaction, views.RocketAbortActionID)
+
+	// The rocket may already be gone
+	if !c.Countdowns.Abort(launch.CountdownID) {
+		middleware.Logger(ctx).Printf("WARNING count down %s is not running", launch.CountdownID)
+		return nil
+	}
+
+	c.Decisions.Record(services.LaunchDecision{
+		Type:    services.LaunchAborted,
+		Actor:   interaction.User.ID,
+		Channel: interaction.Container.ChannelID,
+		Rocket:  launch.Rocket,
+	})
+	c.History.SetOutcome(launch.RecordID, services.OutcomeAborted)
+
+	// The count down message is the one holding the Abort button
+	updater := &MessageUpdater{
+		Client:      clt.GetApiClient(),
+		Channel:     interaction.Container.ChannelID,
+		Timestamp:   interaction.Container.MessageTs,
+		ResponseURL: interaction.ResponseURL,
+		Ephemeral:   launch.Silent,
+	}
+
+	// Replace the count down with the decision
+	err := updater.Update(views.LaunchRocketAborted(interaction.User.ID, launch.Rocket)...)
+
+	if err != nil {
+		return fmt.Errorf("while aborting /rocket: %w", err)
+	}
+
+	return nil
+}
+
+// launchFromValues picks the rocket in the catalog and checks the count down
+// the count down of the rocket is used unless it is given on the command line
+func launchFromValues(values commands.Values, spec commands.Spec, catalog services.RocketCatalog) (views.RocketLaunch, error) {
+	rocket := catalog.DefaultRocket()
+	if name := values.String("rocket"); name != "" {
+		found, ok := catalog.Find(name)
+		if !ok {
+			return views.RocketLaunch{}, &commands.UsageError{
+				Reason: fmt.Sprintf("unknown rocket %q, pick one of: %s", name, strings.Join(catalog.Names(), ", ")),
+				Usage:  spec.Usage(),
+			}
+		}
+		rocket = found
+	}
+
+	launch := views.RocketLaunch{
+		Count:  rocket.Countdown,
+		Rocket: rocket.Name,
+		Silent: values.Bool("silent"),
+	}
+
+	if values.IsSet("count") {
+		launch.Count = values.Int("count")
+	}
+
+	return launch, checkCountDown(launch.Count, spec)
+}
+
+// checkCountDown keeps the count down between 0 and maxCountDown
+func checkCountDown(count int, spec commands.Spec) error {
+	if count < 0 || count > maxCountDown {
+		return &commands.UsageError{
+			Reason: fmt.Sprintf("the count down must be between 0 and %d seconds", maxCountDown),
+			Usage:  spec.Usage(),
+		}
+	}
+
+	return nil
+}
+
+// denyMetadata is kept in the reason modal to update the announcement on submission
+type denyMetadata struct {
+	DecisionID  string `json:"decision_id"`
+	ResponseURL string `json:"response_url"`
+	Channel     string `json:"channel"`
+}
+
+func (c SlashCommandController) denyRocketLaunch(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into a Slash Command
+	interaction := evt.Data.(slack.InteractionCallback)
+
+	// The launch parameters are carried by the deny button
+	launch := c.launchFromAction(interaction, views.RocketDenyActionID)
+
+	// Nobody can approve it anymore, the denial is recorded once even when the handler is retried
+	denied, _ := middleware.Step(ctx, "deny the launch request", func() (interface{}, error) {
+		if request, ok := c.Approvals.Close(launch.RequestID); ok {
+			c.History.Record(services.LaunchRecord{
+				Requester: request.Requester,
+				Approvers: request.Approvers,
+				Rocket:    launch.Rocket,
+				Channel:   interaction.Container.ChannelID,
+				Countdown: launch.Count,
+				Outcome:   services.OutcomeDenied,
+				Silent:    launch.Silent,
+			})
+		}
+
+		return c.Decisions.Record(services.LaunchDecision{
+			Type:    services.LaunchDenied,
+			Actor:   interaction.User.ID,
+			Channel: interaction.Container.ChannelID,
+			Rocket:  launch.Rocket,
+		}), nil
+	})
+	decision := denied.(services.LaunchDecision)
+
+	client := clt.GetApiClient()
+
+	// Replace the announcement with the decision
+	_, _, err := client.PostMessage(
+		interaction.Container.ChannelID,
+		slack.MsgOptionBlocks(views.LaunchRocketDenied(decision.Actor, decision.Rocket, "")...),
+		slack.MsgOptionResponseURL(interaction.ResponseURL, c.announcementResponseType()),
+		slack.MsgOptionReplaceOriginal(interaction.ResponseURL),
+	)
+
+	if err != nil {
+		return fmt.Errorf("while denying /rocket: %w", err)
+	}
+
+	// Ask for a reason, the user can skip it
+	metadata, _ := json.Marshal(denyMetadata{
+		DecisionID:  decision.ID,
+		ResponseURL: interaction.ResponseURL,
+		Channel:     interaction.Container.ChannelID,
+	})
+
+	_, err = client.OpenView(interaction.TriggerID, views.RocketDenyReasonModal(string(metadata)))
+
+	// the trigger expires after 3 seconds, opening the modal again would fail
+	if err != nil {
+		middleware.Logger(ctx).Printf("ERROR unable to open the deny reason modal: %v", err)
+	}
+
+	return nil
+}
+
+func (c SlashCommandController) saveDenyReason(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into slack.InteractionCallback
+	view_submission := evt.Data.(slack.InteractionCallback)
+
+	var metadata denyMetadata
+	if err := json.Unmarshal([]byte(view_submission.View.PrivateMetadata), &metadata); err != nil {
+		return fmt.Errorf("unable to read the deny reason metadata: %w", err)
+	}
+
+	reason := view_submission.View.State.Values[views.RocketDenyReasonBlockID][views.RocketDenyReasonActionID].Value
+	if reason == "" {
+		return nil
+	}
+
+	decision, ok := c.Decisions.SetReason(metadata.DecisionID, reason)
+	if !ok {
+		return fmt.Errorf("unknown launch decision %s", metadata.DecisionID)
+	}
+
+	// Add the reason to the announcement
+	_, _, err := clt.GetApiClient().PostMessage(
+		metadata.Channel,
+		slack.MsgOptionBlocks(views.LaunchRocketDenied(decision.Actor, decision.Rocket, decision.Reason)...),
+		slack.MsgOptionResponseURL(metadata.ResponseURL, c.announcementResponseType()),
+		slack.MsgOptionReplaceOriginal(metadata.ResponseURL),
+	)
+
+	if err != nil {
+		return fmt.Errorf("while sending the deny reason for /rocket: %w", err)
+	}
+
+	return nil
+}
+
+// launchFromAction reads the launch parameters from the value of the clicked button
+// legacy buttons do not carry any parameter so we use the default rocket
+// the rocket picked with the select of the announcement replaces the one of the command with its count down
+func (c SlashCommandController) launchFromAction(interaction slack.InteractionCallback, actionID string) views.RocketLaunch {
+	rocket := c.Catalog.DefaultRocket()
+	launch := views.RocketLaunch{Count: rocket.Countdown, Rocket: rocket.Name}
+
+	for _, action := range interaction.ActionCallback.BlockActions {
+		if action.ActionID != actionID {
+			continue
+		}
+
+		decoded, err := views.DecodeRocketLaunch(action.Value)
+		if err != nil {
+			log.Printf("ERROR unable to decode the launch, using defaults: %v", err)
+			break
+		}
+		launch = decoded
+	}
+
+	if interaction.BlockActionState != nil {
+		selected := interaction.BlockActionState.Values[views.RocketAnnoncementBlockID][views.RocketSelectActionID].SelectedOption.Value
+		if rocket, ok := c.Catalog.Find(selected); ok && rocket.Name != launch.Rocket {
+			launch.Rocket = rocket.Name
+			launch.Count = rocket.Countdown
+		}
+	}
+
+	return launch
+}
+
+// rocket finds the rocket of a launch in the catalog
+// rockets removed from the catalog are displayed like the default one
+func (c SlashCommandController) rocket(name string) services.Rocket {
+	if rocket, ok := c.Catalog.Find(name); ok {
+		return rocket
+	}
+
+	return c.Catalog.DefaultRocket()
+}
+
+// selectRocket only needs the rocket picked in the announcement to be acknowledged
+// it is read from the state of the message when the launch is approved
+func (c SlashCommandController) selectRocket(evt *socketmode.Event, clt *socketmode.Client) {
+}
+
+// loadRocketOptions answers the select of the announcement with the rockets of the catalog
+func (c SlashCommandController) loadRocketOptions(evt *socketmode.Event, clt *socketmode.Client) {
+	suggestion := evt.Data.(slack.InteractionCallback)
+
+	options := []views.RocketOption{}
+	for _, rocket := range c.Catalog.Search(suggestion.Value) {
+		options = append(options, views.RocketOption{Name: rocket.Name, Description: rocket.Description})
 	}
 
+	// The options are sent back with the acknowledgement
+	middleware.Ack(evt, clt, views.RocketOptions(options))
 }
@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// testCatalog adds a Starship to the default catalog
+func testCatalog() services.RocketCatalog {
+	catalog := services.DefaultRocketCatalog()
+	catalog.Rockets = append(catalog.Rockets, services.Rocket{
+		Name:      "Starship",
+		Frames:    []string{"https://example.com/starship0.png"},
+		Countdown: 10,
+	})
+
+	return catalog
+}
+
+func TestLaunchFromValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    views.RocketLaunch
+		wantErr bool
+	}{
+		{
+			name: "Default launch",
+			text: "",
+			want: views.RocketLaunch{Count: 3, Rocket: "Falcon 9"},
+		},
+		{
+			name: "Custom launch",
+			text: `5 --rocket "Starship" --silent`,
+			want: views.RocketLaunch{Count: 5, Rocket: "Starship", Silent: true},
+		},
+		{
+			name: "Count down of the rocket",
+			text: `--rocket starship`,
+			want: views.RocketLaunch{Count: 10, Rocket: "Starship"},
+		},
+		{
+			name:    "Unknown rocket",
+			text:    `--rocket "Saturn V"`,
+			wantErr: true,
+		},
+		{
+			name:    "Count down too long",
+			text:    "3600",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid count down",
+			text:    "soon",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := rocketCommand.Parse(tt.text)
+			got := views.RocketLaunch{}
+			if err == nil {
+				got, err = launchFromValues(values, rocketCommand, testCatalog())
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("launchFromValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); !tt.wantErr && diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSlashCommandController_denyRocketLaunch(t *testing.T) {
+
+	testServer, api := setup_slacktest()
+	defer testServer.Stop()
+
+	// The announcement is replaced through the response URL
+	var replaced int32
+	testServer.Handle("/response", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&replaced, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	// Keep the metadata of the reason modal
+	var metadata string
+	testServer.Handle("/views.open", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			View slack.ModalViewRequest `json:"view"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		metadata = req.View.PrivateMetadata
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	c := SlashCommandController{
+		Decisions: services.NewDecisionLog(),
+		History:   services.NewLaunchHistory(),
+		Catalog:   testCatalog(),
+		Approvals: services.NewLaunchApprovals(services.DefaultApprovalPolicy(), nil, services.SystemClock{}),
+	}
+	launch := views.RocketLaunch{Count: 10, Rocket: "Starship"}
+
+	// When -> Deny is clicked
+	c.denyRocketLaunch(context.Background(), &socketmode.Event{
+		Type: socketmode.EventTypeInteractive,
+		Data: slack.InteractionCallback{
+			Type:        slack.InteractionTypeBlockActions,
+			User:        slack.User{ID: "U0123456"},
+			ResponseURL: testServer.GetAPIURL() + "response",
+			TriggerID:   "trigger",
+			Container:   slack.Container{ChannelID: "C0123456"},
+			ActionCallback: slack.ActionCallbacks{
+				BlockActions: []*slack.BlockAction{
+					{ActionID: views.RocketDenyActionID, Value: launch.Encode()},
+				},
+			},
+		},
+		Request: &socketmode.Request{
+			EnvelopeID: "dummy",
+		},
+	}, soccketClient)
+
+	// Then -> the decision is recorded
+	decisions := c.Decisions.Decisions()
+	if len(decisions) != 1 || decisions[0].Type != services.LaunchDenied || decisions[0].Actor != "U0123456" || decisions[0].Rocket != "Starship" {
+		t.Fatalf("Decisions() = %+v", decisions)
+	}
+
+	// When -> the reason is submitted
+	c.saveDenyReason(context.Background(), &socketmode.Event{
+		Type: socketmode.EventTypeInteractive,
+		Data: slack.InteractionCallback{
+			Type: slack.InteractionTypeViewSubmission,
+			View: slack.View{
+				CallbackID:      views.RocketDenyReasonCallbackID,
+				PrivateMetadata: metadata,
+				State: &slack.ViewState{
+					Values: map[string]map[string]slack.BlockAction{
+						views.RocketDenyReasonBlockID: {
+							views.RocketDenyReasonActionID: {Value: "Too windy"},
+						},
+					},
+				},
+			},
+		},
+		Request: &socketmode.Request{
+			EnvelopeID: "dummy",
+		},
+	}, soccketClient)
+
+	// Then -> the reason is recorded and the announcement replaced twice
+	if got := c.Decisions.Decisions()[0].Reason; got != "Too windy" {
+		t.Errorf("Reason = %v, want %v", got, "Too windy")
+	}
+	if got := atomic.LoadInt32(&replaced); got != 2 {
+		t.Errorf("replaced = %v, want %v", got, 2)
+	}
+}
+
+func TestSlashCommandController_abortRocketLaunch(t *testing.T) {
+
+	mux := http.NewServeMux()
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+
+	// The count down is posted once then updated with chat.update
+	frames := make(chan views.RocketLaunch, 10)
+	frame := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		var blocks slack.Blocks
+		json.Unmarshal([]byte(r.FormValue("blocks")), &blocks)
+
+		launch := views.RocketLaunch{}
+		for _, block := range blocks.BlockSet {
+			if actions, ok := block.(*slack.ActionBlock); ok {
+				value := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement).Value
+				launch, _ = views.DecodeRocketLaunch(value)
+			}
+		}
+		frames <- launch
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"channel":"C0123456","ts":"1.1"}`))
+	}
+	mux.HandleFunc("/chat.postMessage", frame)
+	mux.HandleFunc("/chat.update", frame)
+
+	// The announcement is deleted through the response URL
+	var deleted int32
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deleted, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	nextFrame := func() views.RocketLaunch {
+		select {
+		case launch := <-frames:
+			return launch
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for a frame")
+		}
+		return views.RocketLaunch{}
+	}
+
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	clock := services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	c := SlashCommandController{
+		Decisions:  services.NewDecisionLog(),
+		History:    services.NewLaunchHistory(),
+		Catalog:    testCatalog(),
+		Countdowns: services.NewCountdownService(clock, time.Second),
+		Approvals:  services.NewLaunchApprovals(services.DefaultApprovalPolicy(), nil, clock),
+	}
+	request := c.Approvals.Open("U0123456", "C0123456")
+	launch := views.RocketLaunch{Count: 10, Rocket: "Starship", RequestID: request.ID}
+
+	interaction := func(actionID string, value string) *socketmode.Event {
+		return &socketmode.Event{
+			Type: socketmode.EventTypeInteractive,
+			Data: slack.InteractionCallback{
+				Type:        slack.InteractionTypeBlockActions,
+				User:        slack.User{ID: "U0123456"},
+				ResponseURL: testServer.URL + "/response",
+				Container:   slack.Container{ChannelID: "C0123456", MessageTs: "1.1"},
+				ActionCallback: slack.ActionCallbacks{
+					BlockActions: []*slack.BlockAction{
+						{ActionID: actionID, Value: value},
+					},
+				},
+			},
+			Request: &socketmode.Request{
+				EnvelopeID: "dummy",
+			},
+		}
+	}
+
+	// When -> Approve is clicked, the count down starts without blocking the handler
+	c.launchRocket(context.Background(), interaction(views.RocketAnnoncementActionID, launch.Encode()), soccketClient)
+	nextFrame()
+	clock.Advance(time.Second)
+	running := nextFrame()
+
+	if running.CountdownID == "" {
+		t.Fatalf("the frame should carry the count down, got %+v", running)
+	}
+
+	// When -> Abort is clicked
+	c.abortRocketLaunch(context.Background(), interaction(views.RocketAbortActionID, running.Encode()), soccketClient)
+
+	// Then -> the count down is replaced and stops
+	nextFrame()
+	clock.Advance(time.Second)
+	select {
+	case frame := <-frames:
+		t.Errorf("unexpected frame %+v after abort", frame)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if got := atomic.LoadInt32(&deleted); got != 1 {
+		t.Errorf("deleted = %v, want %v", got, 1)
+	}
+
+	decisions := c.Decisions.Decisions()
+	if len(decisions) != 2 || decisions[1].Type != services.LaunchAborted || decisions[1].Rocket != "Starship" {
+		t.Errorf("Decisions() = %+v", decisions)
+	}
+
+	// The launch history keeps the outcome
+	if recent := c.History.Recent(1); len(recent) != 1 || recent[0].Outcome != services.OutcomeAborted || recent[0].Requester != "U0123456" {
+		t.Errorf("Recent() = %+v", recent)
+	}
+}
+
+func TestSlashCommandController_launchRocket_Approvals(t *testing.T) {
+
+	// Record the messages sent through the response URL
+	var messages []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ResponseType    string `json:"response_type"`
+			ReplaceOriginal bool   `json:"replace_original"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		messages = append(messages, fmt.Sprintf("%s replace:%v", msg.ResponseType, msg.ReplaceOriginal))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+	// The count down is posted in the channel
+	var launched int32
+	mux.HandleFunc("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&launched, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"channel":"C0123456","ts":"1.1"}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	clock := services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	policy := services.ApprovalPolicy{Approvers: 2, ExpiryMinutes: 30}
+	c := SlashCommandController{
+		Decisions:  services.NewDecisionLog(),
+		History:    services.NewLaunchHistory(),
+		Catalog:    testCatalog(),
+		Countdowns: services.NewCountdownService(clock, time.Second),
+		Approvals:  services.NewLaunchApprovals(policy, nil, clock),
+	}
+	request := c.Approvals.Open("UREQUESTER", "C0123456")
+	launch := views.RocketLaunch{Count: 0, Rocket: "Starship", RequestID: request.ID}
+
+	approve := func(user string) {
+		c.launchRocket(context.Background(), &socketmode.Event{
+			Type: socketmode.EventTypeInteractive,
+			Data: slack.InteractionCallback{
+				Type:        slack.InteractionTypeBlockActions,
+				User:        slack.User{ID: user},
+				ResponseURL: testServer.URL + "/response",
+				Container:   slack.Container{ChannelID: "C0123456"},
+				ActionCallback: slack.ActionCallbacks{
+					BlockActions: []*slack.BlockAction{
+						{ActionID: views.RocketAnnoncementActionID, Value: launch.Encode()},
+					},
+				},
+			},
+			Request: &socketmode.Request{
+				EnvelopeID: "dummy",
+			},
+		}, soccketClient)
+	}
+
+	// The requester is told they cannot approve, the others update the announcement
+	approve("UREQUESTER")
+	approve("UALICE")
+	if diff := deep.Equal(messages, []string{"ephemeral replace:false", "in_channel replace:true"}); diff != nil {
+		t.Error(diff)
+	}
+	if got := len(c.Decisions.Decisions()); got != 0 {
+		t.Errorf("Decisions() = %v, the launch should wait for the quorum", got)
+	}
+
+	// The second approval launches the rocket
+	approve("UBOB")
+
+	decisions := c.Decisions.Decisions()
+	if len(decisions) != 1 || decisions[0].Actor != "UBOB" {
+		t.Fatalf("Decisions() = %+v", decisions)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&launched) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&launched) == 0 {
+		t.Error("the count down did not start")
+	}
+
+	// The requester and the approvers are kept in the history
+	recent := c.History.Recent(1)
+	if len(recent) != 1 || recent[0].Requester != "UREQUESTER" {
+		t.Fatalf("Recent() = %+v", recent)
+	}
+	if diff := deep.Equal(recent[0].Approvers, []string{"UALICE", "UBOB"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestSlashCommandController_launchFromAction(t *testing.T) {
+	c := SlashCommandController{Catalog: testCatalog()}
+	launch := views.RocketLaunch{Count: 5, Rocket: "Falcon 9", RequestID: "1"}
+
+	interaction := slack.InteractionCallback{
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{
+				{ActionID: views.RocketAnnoncementActionID, Value: launch.Encode()},
+			},
+		},
+	}
+
+	// The rocket of the command
+	if diff := deep.Equal(c.launchFromAction(interaction, views.RocketAnnoncementActionID), launch); diff != nil {
+		t.Error(diff)
+	}
+
+	// The rocket picked in the announcement
+	interaction.BlockActionState = &slack.BlockActionStates{
+		Values: map[string]map[string]slack.BlockAction{
+			views.RocketAnnoncementBlockID: {
+				views.RocketSelectActionID: {SelectedOption: slack.OptionBlockObject{Value: "Starship"}},
+			},
+		},
+	}
+
+	want := launch
+	want.Rocket = "Starship"
+	want.Count = 10
+	if diff := deep.Equal(c.launchFromAction(interaction, views.RocketAnnoncementActionID), want); diff != nil {
+		t.Error(diff)
+	}
+
+	// Legacy buttons launch the default rocket
+	if got := c.launchFromAction(slack.InteractionCallback{}, views.RocketAnnoncementActionID); got.Rocket != "Falcon 9" || got.Count != 3 {
+		t.Errorf("launchFromAction() = %+v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/views"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// SlashCommandRouter runs declarative slash commands on top of HandleSlashCommand
+// it picks the subcommand, checks the ACLs, parses the arguments and answers
+// `help` and the errors with an ephemeral message
+type SlashCommandRouter struct {
+	EventHandler *middleware.Router
+	// ACLs replace the ACL of the definitions, e.g. read from a file
+	ACLs commands.ACLs
+	// Groups checks the user groups of the ACLs, the slack API of the client by default
+	Groups commands.UserGroupMembersGetter
+
+	commands map[string]*commands.Command
+	order    []*commands.Command
+}
+
+func NewSlashCommandRouter(eventhandler *middleware.Router, acls commands.ACLs) *SlashCommandRouter {
+	return &SlashCommandRouter{
+		EventHandler: eventhandler,
+		ACLs:         acls,
+		commands:     make(map[string]*commands.Command),
+	}
+}
+
+// Register validates a command and handles it
+// An invalid definition is a programming error so we panic
+func (r *SlashCommandRouter) Register(command *commands.Command) {
+	if err := command.Validate(); err != nil {
+		panic(err)
+	}
+
+	r.ACLs.Apply(command)
+
+	r.commands[command.Spec.Command] = command
+	r.order = append(r.order, command)
+
+	r.EventHandler.HandleSlashCommand(command.Spec.Command, r.EventHandler.WithContext(r.Handle, 0), middleware.AutoAck)
+}
+
+// Manifest lists the metadata of the registered commands for the App manifest
+func (r *SlashCommandRouter) Manifest() []commands.ManifestCommand {
+	manifest := []commands.ManifestCommand{}
+	for _, command := range r.order {
+		manifest = append(manifest, command.Manifest())
+	}
+
+	return manifest
+}
+
+// Handle runs the registered command matching the slash command
+func (r *SlashCommandRouter) Handle(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+	// we need to cast our socketmode.Event into a Slash Command
+	command, ok := evt.Data.(slack.SlashCommand)
+
+	if ok != true {
+		return errors.New("converting event to Slash Command")
+	}
+
+	definition, ok := r.commands[command.Command]
+	if !ok {
+		return fmt.Errorf("no definition for %s", command.Command)
+	}
+
+	path, args := definition.Resolve(command.Text)
+	target := path[len(path)-1]
+
+	if err := r.run(ctx, path, args, command, clt); err != nil {
+		return fmt.Errorf("while running %s: %w", target.Spec.Command, err)
+	}
+
+	return nil
+}
+
+// run checks and runs the last command of the path
+// every answer of the router is ephemeral
+func (r *SlashCommandRouter) run(ctx context.Context, path []*commands.Command, args string, command slack.SlashCommand, clt *socketmode.Client) error {
+	target := path[len(path)-1]
+
+	// The ACL of the parent commands apply to their subcommands, and to their help
+	for _, c := range path {
+		allowed, err := c.ACL.Allows(command.UserID, command.ChannelID, r.groups(clt))
+		if err != nil {
+			r.reply(command, clt, views.SlashCommandError(path[0].Spec.Command, "your permissions could not be checked, please try again"))
+			return err
+		}
+		if !allowed {
+			return r.reply(command, clt, views.SlashCommandError(path[0].Spec.Command, fmt.Sprintf("you are not allowed to use `%s` here", c.Spec.Command)))
+		}
+	}
+
+	// A group of subcommands explains what can be run
+	if commands.IsHelp(args) || target.Run == nil {
+		return r.reply(command, clt, helpView(target))
+	}
+
+	values, err := target.Spec.Parse(args)
+	if err == nil {
+		err = target.Run(commands.Request{Context: ctx, Command: command, Values: values, Client: clt})
+	}
+
+	if usage, ok := err.(*commands.UsageError); ok {
+		// Explain the user how to use the command
+		return r.reply(command, clt, views.SlashCommandUsage(usage.Reason, usage.Usage))
+	}
+
+	// The user is told once even when the command is retried
+	if err != nil {
+		middleware.Step(ctx, "failure reply", func() (interface{}, error) {
+			return nil, r.reply(command, clt, views.SlashCommandError(path[0].Spec.Command, fmt.Sprintf("`%s` failed, please try again", target.Spec.Command)))
+		})
+	}
+
+	return err
+}
+
+// reply answers the user who ran the command
+func (r *SlashCommandRouter) reply(command slack.SlashCommand, clt *socketmode.Client, blocks []slack.Block) error {
+	_, _, err := clt.GetApiClient().PostMessage(
+		command.ChannelID,
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	return err
+}
+
+func (r *SlashCommandRouter) groups(clt *socketmode.Client) commands.UserGroupMembersGetter {
+	if r.Groups != nil {
+		return r.Groups
+	}
+
+	return clt.GetApiClient()
+}
+
+// helpView lists the usage of a command and of its subcommands
+func helpView(command *commands.Command) []slack.Block {
+	entries := []views.CommandHelp{}
+	for _, entry := range command.Help() {
+		entries = append(entries, views.CommandHelp{Usage: entry.Usage, Description: entry.Description})
+	}
+
+	return views.SlashCommandHelp(command.Spec.Command, entries)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestSlashCommandRouter_Handle(t *testing.T) {
+
+	// Every answer of the router goes through the response URL
+	var mu sync.Mutex
+	responses := []string{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		responses = append(responses, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	soccketClient := socketmode.New(
+		slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/")),
+	)
+
+	var ran []string
+	router := NewSlashCommandRouter(middleware.NewRouter(socketmode.NewsSocketmodeHandler(soccketClient)), commands.ACLs{
+		"/demo secret": {Users: []string{"UADMIN"}},
+	})
+	router.Register(&commands.Command{
+		Spec:        commands.Spec{Command: "/demo", Args: []commands.Arg{{Name: "count", Type: commands.TypeInt}}},
+		Description: "Run the demo",
+		Run: func(req commands.Request) error {
+			ran = append(ran, "demo")
+			return nil
+		},
+		Subcommands: []*commands.Command{
+			{
+				Spec: commands.Spec{Command: "/demo secret"},
+				Run: func(req commands.Request) error {
+					ran = append(ran, "secret")
+					return nil
+				},
+			},
+			{
+				Spec: commands.Spec{Command: "/demo broken"},
+				Run: func(req commands.Request) error {
+					return errors.New("boom")
+				},
+			},
+		},
+	})
+
+	handle := func(user string, text string) string {
+		mu.Lock()
+		responses = []string{}
+		mu.Unlock()
+
+		router.Handle(context.Background(), &socketmode.Event{
+			Type: socketmode.EventTypeSlashCommand,
+			Data: slack.SlashCommand{
+				Command:     "/demo",
+				Text:        text,
+				UserID:      user,
+				ChannelID:   "C0123456",
+				ResponseURL: testServer.URL + "/response",
+			},
+			Request: &socketmode.Request{EnvelopeID: "dummy"},
+		}, soccketClient)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(responses, "\n")
+	}
+
+	tests := []struct {
+		name string
+		user string
+		text string
+		want string
+	}{
+		{name: "Help", user: "U1", text: "help", want: "How to use `/demo`"},
+		{name: "Usage error", user: "U1", text: "ten", want: "is not a number"},
+		{name: "Not allowed", user: "U1", text: "secret", want: "you are not allowed to use `/demo secret` here"},
+		{name: "Help not allowed", user: "U1", text: "secret help", want: "you are not allowed to use `/demo secret` here"},
+		{name: "Help allowed", user: "UADMIN", text: "secret help", want: "How to use `/demo secret`"},
+		{name: "Failure", user: "U1", text: "broken", want: "`/demo broken` failed"},
+		{name: "Run", user: "UADMIN", text: "secret", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := handle(tt.user, tt.text)
+			if tt.want == "" && got != "" {
+				t.Errorf("unexpected answer %s", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("answer = %s, want %q", got, tt.want)
+			}
+			if tt.want != "" && !strings.Contains(got, `"response_type":"ephemeral"`) {
+				t.Errorf("answer is not ephemeral: %s", got)
+			}
+		})
+	}
+
+	if got := strings.Join(ran, ","); got != "secret" {
+		t.Errorf("ran = %v, want secret", got)
+	}
+
+	if manifest := router.Manifest(); len(manifest) != 1 || manifest[0].UsageHint != "[count] | secret | broken | help" {
+		t.Errorf("Manifest() = %+v", manifest)
+	}
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"log"
+	"time"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// rocketAtCommand schedules a launch at a time of the requester's day
+// e.g. /rocket at 15:30 10 --rocket "Starship"
+var rocketAtCommand = commands.Spec{
+	Command: "/rocket at",
+	Args: []commands.Arg{
+		{Name: "time", Type: commands.TypeString, Required: true, Usage: "time of the launch in your timezone, e.g. 15:30"},
+		countArg,
+	},
+	Flags: rocketFlags,
+}
+
+// rocketInCommand schedules a launch after a delay
+// e.g. /rocket in 2h
+var rocketInCommand = commands.Spec{
+	Command: "/rocket in",
+	Args: []commands.Arg{
+		{Name: "delay", Type: commands.TypeDuration, Required: true, Usage: "delay before the launch, e.g. 2h30m"},
+		countArg,
+	},
+	Flags: rocketFlags,
+}
+
+var rocketListCommand = commands.Spec{
+	Command: "/rocket list",
+}
+
+var rocketCancelCommand = commands.Spec{
+	Command: "/rocket cancel",
+	Args: []commands.Arg{
+		{Name: "id", Type: commands.TypeString, Required: true, Usage: "ID of the scheduled launch"},
+	},
+}
+
+// maxScheduleDelay keeps the scheduled launches in a reasonable future
+const maxScheduleDelay = 7 * 24 * time.Hour
+
+// scheduleTimeFormat is how the launch time is shown to the users
+const scheduleTimeFormat = "Mon Jan 2 15:04 MST"
+
+// scheduledLaunchFromValues reads the launch and its time from the arguments of `/rocket at` or `/rocket in`
+func scheduledLaunchFromValues(values commands.Values, spec commands.Spec, now time.Time, loc *time.Location, catalog services.RocketCatalog) (views.RocketLaunch, time.Time, error) {
+	launch, err := launchFromValues(values, spec, catalog)
+	if err != nil {
+		return launch, time.Time{}, err
+	}
+
+	var at time.Time
+	if spec.Command == rocketAtCommand.Command {
+		at, err = services.NextWallClock(now, values.String("time"), loc)
+		if err != nil {
+			return launch, at, &commands.UsageError{Reason: err.Error(), Usage: spec.Usage()}
+		}
+	} else {
+		delay := values.Duration("delay")
+		if delay <= 0 || delay > maxScheduleDelay {
+			return launch, at, &commands.UsageError{
+				Reason: "the delay must be positive and at most 7 days",
+				Usage:  spec.Usage(),
+			}
+		}
+		at = now.Add(delay)
+	}
+
+	return launch, at, nil
+}
+
+// scheduleRocketLaunch posts the announcement later on
+// spec is either `/rocket at` or `/rocket in`
+func (c SlashCommandController) scheduleRocketLaunch(spec commands.Spec) commands.RunFunc {
+	return func(req commands.Request) error {
+		return c.scheduleRocketLaunchWith(req, spec)
+	}
+}
+
+func (c SlashCommandController) scheduleRocketLaunchWith(req commands.Request, spec commands.Spec) error {
+	command, clt := req.Command, req.Client
+
+	// Times are given in the timezone of the requester
+	loc := c.userLocation(command.UserID)
+
+	launch, at, err := scheduledLaunchFromValues(req.Values, spec, c.Scheduler.Now(), loc, c.Catalog)
+	if err != nil {
+		return err
+	}
+
+	// The launch is scheduled once even when the confirmation is retried
+	result, _ := middleware.Step(req.Context, "schedule the launch", func() (interface{}, error) {
+		return c.Scheduler.Schedule(services.ScheduledLaunch{
+			Requester: command.UserID,
+			Channel:   command.ChannelID,
+			At:        at,
+			Launch:    launch.Encode(),
+		}, func(scheduled services.ScheduledLaunch) {
+			c.postScheduledAnnouncement(scheduled, clt)
+		}), nil
+	})
+	scheduled := result.(services.ScheduledLaunch)
+
+	// Confirm to the requester
+	_, _, err = clt.GetApiClient().PostMessage(
+		command.ChannelID,
+		slack.MsgOptionBlocks(views.RocketLaunchScheduled(scheduledView(scheduled, launch, loc))...),
+		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	return err
+}
+
+// postScheduledAnnouncement opens the launch request once the time has come
+// the response URL of the command has expired so the App posts by itself
+func (c SlashCommandController) postScheduledAnnouncement(scheduled services.ScheduledLaunch, clt *socketmode.Client) {
+	launch, err := views.DecodeRocketLaunch(scheduled.Launch)
+	if err != nil {
+		log.Printf("ERROR unable to decode the scheduled launch %s: %v", scheduled.ID, err)
+		return
+	}
+
+	request := c.Approvals.Open(scheduled.Requester, scheduled.Channel)
+	launch.RequestID = request.ID
+
+	blocks := views.LaunchRocketAnnoncement(launch, c.rocket(launch.Rocket).Description, c.approval(request))
+	client := clt.GetApiClient()
+
+	if c.Approvals.Policy().Shared() {
+		_, _, err = client.PostMessage(scheduled.Channel, slack.MsgOptionBlocks(blocks...))
+	} else {
+		_, err = client.PostEphemeral(scheduled.Channel, scheduled.Requester, slack.MsgOptionBlocks(blocks...))
+	}
+
+	if err != nil {
+		log.Printf("ERROR while announcing the scheduled launch %s: %v", scheduled.ID, err)
+	}
+}
+
+// listScheduledLaunches shows the pending launches of the channel
+func (c SlashCommandController) listScheduledLaunches(req commands.Request) error {
+	command, clt := req.Command, req.Client
+
+	loc := c.userLocation(command.UserID)
+
+	launches := []views.ScheduledRocketLaunch{}
+	for _, scheduled := range c.Scheduler.Pending(command.ChannelID) {
+		launch, _ := views.DecodeRocketLaunch(scheduled.Launch)
+		launches = append(launches, scheduledView(scheduled, launch, loc))
+	}
+
+	_, _, err := clt.GetApiClient().PostMessage(
+		command.ChannelID,
+		slack.MsgOptionBlocks(views.ScheduledRocketLaunches(launches)...),
+		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	return err
+}
+
+// cancelScheduledLaunch removes a pending launch of the requester
+func (c SlashCommandController) cancelScheduledLaunch(req commands.Request) error {
+	command, clt := req.Command, req.Client
+
+	// A retried confirmation does not find the launch cancelled by the previous attempt
+	result, err := middleware.Step(req.Context, "cancel the launch", func() (interface{}, error) {
+		return c.Scheduler.Cancel(req.Values.String("id"), command.UserID)
+	})
+	if err != nil {
+		return &commands.UsageError{Reason: err.Error(), Usage: rocketCancelCommand.Usage()}
+	}
+	scheduled := result.(services.ScheduledLaunch)
+
+	launch, _ := views.DecodeRocketLaunch(scheduled.Launch)
+
+	_, _, err = clt.GetApiClient().PostMessage(
+		command.ChannelID,
+		slack.MsgOptionBlocks(views.RocketLaunchCancelled(scheduledView(scheduled, launch, c.userLocation(command.UserID)))...),
+		slack.MsgOptionResponseURL(command.ResponseURL, slack.ResponseTypeEphemeral),
+	)
+
+	return err
+}
+
+// userLocation is the timezone of a user, UTC when it cannot be read
+func (c SlashCommandController) userLocation(user string) *time.Location {
+	info, err := c.Directory.GetUserInfo(user)
+	if err != nil {
+		log.Printf("WARNING unable to read the timezone of %s, using UTC: %v", user, err)
+		return time.UTC
+	}
+
+	return services.UserLocation(info)
+}
+
+// scheduledView formats a scheduled launch for the user reading it
+func scheduledView(scheduled services.ScheduledLaunch, launch views.RocketLaunch, loc *time.Location) views.ScheduledRocketLaunch {
+	return views.ScheduledRocketLaunch{
+		ID:        scheduled.ID,
+		Requester: scheduled.Requester,
+		Rocket:    launch.Rocket,
+		At:        scheduled.At.In(loc).Format(scheduleTimeFormat),
+	}
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/commands"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
+	"xnok/slack-go-demo/views"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestScheduledLaunchFromValues(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+
+	// 12:00 UTC is 14:00 in Paris
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		text    string
+		want    views.RocketLaunch
+		wantAt  time.Time
+		wantErr bool
+	}{
+		{
+			name:   "At a time of the day",
+			text:   "at 15:30",
+			want:   views.RocketLaunch{Count: 3, Rocket: "Falcon 9"},
+			wantAt: time.Date(2021, 5, 8, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "After a delay",
+			text:   `in 2h 10 --rocket "Starship"`,
+			want:   views.RocketLaunch{Count: 10, Rocket: "Starship"},
+			wantAt: time.Date(2021, 5, 8, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Missing time",
+			text:    "at",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid time",
+			text:    "at noon",
+			wantErr: true,
+		},
+		{
+			name:    "Delay too long",
+			text:    "in 200h",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, rest := commands.SplitSubCommand(tt.text)
+			spec := rocketInCommand
+			if sub == "at" {
+				spec = rocketAtCommand
+			}
+
+			values, err := spec.Parse(rest)
+			got, at := views.RocketLaunch{}, time.Time{}
+			if err == nil {
+				got, at, err = scheduledLaunchFromValues(values, spec, now, paris, testCatalog())
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scheduledLaunchFromValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+			if !at.Equal(tt.wantAt) {
+				t.Errorf("scheduledLaunchFromValues() at = %v, want %v", at, tt.wantAt)
+			}
+		})
+	}
+}
+
+func TestSlashCommandController_scheduleRocketLaunch(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"user":{"id":"U0123456","tz":"Europe/Paris"}}`))
+	})
+	// The confirmation and the list are sent through the response URL
+	var responses int32
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&responses, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+	// The announcement is posted by the App when the time has come
+	var announced int32
+	mux.HandleFunc("/chat.postEphemeral", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&announced, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"message_ts":"1.1"}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	api := slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/"))
+	soccketClient := socketmode.New(
+		api,
+	)
+
+	clock := services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	c := SlashCommandController{
+		Decisions: services.NewDecisionLog(),
+		History:   services.NewLaunchHistory(),
+		Catalog:   testCatalog(),
+		Approvals: services.NewLaunchApprovals(services.DefaultApprovalPolicy(), nil, clock),
+		Scheduler: services.NewLaunchScheduler(clock),
+		Directory: services.NewDirectory(api, services.DefaultDirectoryTTL),
+	}
+
+	router := NewSlashCommandRouter(middleware.NewRouter(socketmode.NewsSocketmodeHandler(soccketClient)), nil)
+	router.Register(c.rocketCommands())
+
+	command := func(text string) *socketmode.Event {
+		return &socketmode.Event{
+			Type: socketmode.EventTypeSlashCommand,
+			Data: slack.SlashCommand{
+				Command:     "/rocket",
+				Text:        text,
+				UserID:      "U0123456",
+				ChannelID:   "C0123456",
+				ResponseURL: testServer.URL + "/response",
+			},
+			Request: &socketmode.Request{
+				EnvelopeID: "dummy",
+			},
+		}
+	}
+
+	// When -> two launches are scheduled and one is cancelled
+	router.Handle(context.Background(), command("at 15:30"), soccketClient)
+	router.Handle(context.Background(), command("in 3h"), soccketClient)
+	router.Handle(context.Background(), command("cancel 2"), soccketClient)
+
+	if got := atomic.LoadInt32(&responses); got != 3 {
+		t.Errorf("responses = %v, want %v", got, 3)
+	}
+	if pending := c.Scheduler.Pending("C0123456"); len(pending) != 1 || pending[0].ID != "1" {
+		t.Errorf("Pending() = %+v", pending)
+	}
+
+	// Then -> the announcement is posted at 15:30 in Paris
+	clock.Advance(time.Hour)
+	if got := atomic.LoadInt32(&announced); got != 0 {
+		t.Errorf("announced = %v before the time", got)
+	}
+
+	clock.Advance(30 * time.Minute)
+	if got := atomic.LoadInt32(&announced); got != 1 {
+		t.Errorf("announced = %v, want %v", got, 1)
+	}
+	if pending := c.Approvals.Pending(); len(pending) != 1 || pending[0].Requester != "U0123456" {
+		t.Errorf("the launch request should be opened, got %+v", pending)
+	}
+}
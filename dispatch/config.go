@@ -0,0 +1,107 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// OverflowPolicy is what happens to a new job when its queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for a worker to free some room
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest forgets the oldest job of the queue
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowReject refuses the new job
+	OverflowReject OverflowPolicy = "reject"
+)
+
+// QueueConfig bounds the queue of an event type
+type QueueConfig struct {
+	Limit    int            `json:"limit"`
+	Overflow OverflowPolicy `json:"overflow"`
+}
+
+// Config is the size of the worker pool and the limits of the queues
+// Queues are configured by event type, the others use Default
+type Config struct {
+	Workers int                    `json:"workers"`
+	Default QueueConfig            `json:"default"`
+	Queues  map[string]QueueConfig `json:"queues,omitempty"`
+	// ShardBy names what the events handled in order share, e.g. `user`, `channel` or `view`
+	// Every event runs as soon as a worker is free when it is empty
+	ShardBy string `json:"shard_by,omitempty"`
+}
+
+// DefaultConfig lets a few handlers run at once, the event loop waits when a queue is full
+// the events of a user are handled in order
+func DefaultConfig() Config {
+	return Config{
+		Workers: 8,
+		Default: QueueConfig{Limit: 100, Overflow: OverflowBlock},
+		ShardBy: "user",
+	}
+}
+
+// LoadConfig reads the dispatcher configuration file
+// When no file is provided we use the DefaultConfig
+func LoadConfig(file string) (Config, error) {
+	if file == "" {
+		return DefaultConfig(), nil
+	}
+
+	config := DefaultConfig()
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(str, &config); err != nil {
+		return config, err
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks the pool has workers and the queues can hold jobs
+func (c Config) Validate() error {
+	if c.Workers < 1 {
+		return fmt.Errorf("the dispatcher needs at least 1 worker, got %d", c.Workers)
+	}
+
+	if err := c.Default.validate("default"); err != nil {
+		return err
+	}
+
+	for name, queue := range c.Queues {
+		if err := queue.validate(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q QueueConfig) validate(name string) error {
+	if q.Limit < 1 {
+		return fmt.Errorf("queue %s must hold at least 1 event, got %d", name, q.Limit)
+	}
+
+	switch q.Overflow {
+	case OverflowBlock, OverflowDropOldest, OverflowReject:
+		return nil
+	}
+
+	return fmt.Errorf("queue %s has an unknown overflow policy %q", name, q.Overflow)
+}
+
+func (c Config) queueConfig(eventType string) QueueConfig {
+	if queue, ok := c.Queues[eventType]; ok {
+		return queue
+	}
+
+	return c.Default
+}
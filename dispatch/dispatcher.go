@@ -0,0 +1,282 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrQueueFull = errors.New("the queue is full")
+	ErrStopped   = errors.New("the dispatcher is stopped")
+)
+
+// QueueMetrics describes the activity of a queue
+type QueueMetrics struct {
+	Depth     int
+	MaxDepth  int
+	Submitted int
+	Processed int
+	Dropped   int
+	Rejected  int
+}
+
+// job is a function waiting for a worker
+// jobs sharing a key run one after the other in the order they were submitted
+type job struct {
+	seq uint64
+	key string
+	run func()
+}
+
+// queue holds the jobs of an event type waiting for a worker
+type queue struct {
+	name    string
+	config  QueueConfig
+	jobs    []*job
+	metrics QueueMetrics
+}
+
+// Dispatcher runs jobs on a fixed pool of workers
+// Jobs wait in one queue per event type, the workers take them from the queues in turn
+// so a burst of one event type does not starve the others
+// Jobs submitted with a key wait for the previous jobs of the same key, whatever their queue
+type Dispatcher struct {
+	config Config
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queues   map[string]*queue
+	order    []*queue
+	cursor   int
+	stopped  bool
+	seq      uint64
+	// keys holds the jobs of each key in order, the first one is running or next to run
+	keys map[string][]uint64
+
+	wg sync.WaitGroup
+}
+
+func NewDispatcher(config Config) *Dispatcher {
+	d := &Dispatcher{
+		config: config,
+		queues: make(map[string]*queue),
+		keys:   make(map[string][]uint64),
+	}
+	d.notEmpty = sync.NewCond(&d.mu)
+	d.notFull = sync.NewCond(&d.mu)
+
+	return d
+}
+
+// Start launches the workers
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Stop refuses new jobs and waits for the workers to run the queued ones
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	d.notEmpty.Broadcast()
+	d.notFull.Broadcast()
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// Shutdown stops the dispatcher like Stop but gives up waiting when ctx is done
+// the jobs still queued or running are then abandoned
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Submit queues a job for the given event type
+// When the queue is full the overflow policy of the queue applies
+func (d *Dispatcher) Submit(eventType string, run func()) error {
+	return d.SubmitKeyed(eventType, "", run)
+}
+
+// SubmitKeyed queues a job that runs after the jobs previously submitted with the same key
+// e.g. the events of a user are handled in order while other users are handled in parallel
+// Jobs without key run as soon as a worker is free
+func (d *Dispatcher) SubmitKeyed(eventType string, key string, run func()) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return ErrStopped
+	}
+
+	q := d.queue(eventType)
+
+	for len(q.jobs) >= q.config.Limit {
+		switch q.config.Overflow {
+		case OverflowDropOldest:
+			d.forget(q.jobs[0])
+			q.jobs = q.jobs[1:]
+			q.metrics.Dropped++
+			log.Printf("WARNING queue %s is full, dropping its oldest event", eventType)
+		case OverflowReject:
+			q.metrics.Rejected++
+			log.Printf("WARNING queue %s is full, rejecting the event", eventType)
+			return ErrQueueFull
+		default:
+			d.notFull.Wait()
+			if d.stopped {
+				return ErrStopped
+			}
+		}
+	}
+
+	d.seq++
+	j := &job{seq: d.seq, key: key, run: run}
+	if key != "" {
+		d.keys[key] = append(d.keys[key], j.seq)
+	}
+
+	q.jobs = append(q.jobs, j)
+	q.metrics.Submitted++
+	if len(q.jobs) > q.metrics.MaxDepth {
+		q.metrics.MaxDepth = len(q.jobs)
+	}
+
+	d.notEmpty.Signal()
+
+	return nil
+}
+
+// Metrics returns the metrics of every queue by event type
+func (d *Dispatcher) Metrics() map[string]QueueMetrics {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	metrics := map[string]QueueMetrics{}
+	for name, q := range d.queues {
+		m := q.metrics
+		m.Depth = len(q.jobs)
+		metrics[name] = m
+	}
+
+	return metrics
+}
+
+// queue returns the queue of an event type, the dispatcher must be locked
+func (d *Dispatcher) queue(eventType string) *queue {
+	if q, ok := d.queues[eventType]; ok {
+		return q
+	}
+
+	q := &queue{name: eventType, config: d.config.queueConfig(eventType)}
+	d.queues[eventType] = q
+	d.order = append(d.order, q)
+
+	// a stable order keeps the round robin predictable
+	sort.Slice(d.order, func(i, j int) bool { return d.order[i].name < d.order[j].name })
+
+	return q
+}
+
+// next takes the next job that can run, visiting the queues in turn, the dispatcher must be locked
+func (d *Dispatcher) next() (*job, *queue) {
+	for i := 0; i < len(d.order); i++ {
+		q := d.order[(d.cursor+i)%len(d.order)]
+
+		for n, j := range q.jobs {
+			if !d.ready(j) {
+				continue
+			}
+
+			d.cursor = (d.cursor + i + 1) % len(d.order)
+
+			q.jobs = append(q.jobs[:n:n], q.jobs[n+1:]...)
+			return j, q
+		}
+	}
+
+	return nil, nil
+}
+
+// ready tells if the job is the first of its key, the dispatcher must be locked
+func (d *Dispatcher) ready(j *job) bool {
+	return j.key == "" || d.keys[j.key][0] == j.seq
+}
+
+// forget removes a job from the jobs of its key, the dispatcher must be locked
+func (d *Dispatcher) forget(j *job) {
+	if j.key == "" {
+		return
+	}
+
+	seqs := d.keys[j.key]
+	for n, seq := range seqs {
+		if seq == j.seq {
+			seqs = append(seqs[:n:n], seqs[n+1:]...)
+			break
+		}
+	}
+
+	if len(seqs) == 0 {
+		delete(d.keys, j.key)
+		return
+	}
+	d.keys[j.key] = seqs
+}
+
+// empty tells if every queue is empty, the dispatcher must be locked
+func (d *Dispatcher) empty() bool {
+	for _, q := range d.order {
+		if len(q.jobs) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		j, q := d.next()
+		for j == nil {
+			if d.stopped && d.empty() {
+				d.mu.Unlock()
+				return
+			}
+			d.notEmpty.Wait()
+			j, q = d.next()
+		}
+		d.notFull.Broadcast()
+		d.mu.Unlock()
+
+		j.run()
+
+		d.mu.Lock()
+		q.metrics.Processed++
+		d.forget(j)
+		// the next job of the key may be waiting for another worker
+		if j.key != "" {
+			d.notEmpty.Broadcast()
+		}
+		d.mu.Unlock()
+	}
+}
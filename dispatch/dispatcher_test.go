@@ -0,0 +1,317 @@
+package dispatch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestDispatcherRunsJobs(t *testing.T) {
+	d := NewDispatcher(DefaultConfig())
+	d.Start()
+
+	var mu sync.Mutex
+	ran := 0
+	for i := 0; i < 20; i++ {
+		if err := d.Submit("events_api app_home_opened", func() {
+			mu.Lock()
+			ran++
+			mu.Unlock()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Stop waits for the queued jobs
+	d.Stop()
+
+	if ran != 20 {
+		t.Errorf("ran %d jobs, want 20", ran)
+	}
+
+	metrics := d.Metrics()["events_api app_home_opened"]
+	if metrics.Submitted != 20 || metrics.Processed != 20 || metrics.Depth != 0 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+
+	if err := d.Submit("events_api app_home_opened", func() {}); err != ErrStopped {
+		t.Errorf("Submit after Stop returned %v", err)
+	}
+}
+
+func TestDispatcherBoundsWorkers(t *testing.T) {
+	d := NewDispatcher(Config{Workers: 2, Default: QueueConfig{Limit: 10, Overflow: OverflowBlock}})
+	d.Start()
+
+	var mu sync.Mutex
+	running, max := 0, 0
+	for i := 0; i < 10; i++ {
+		d.Submit("interactive", func() {
+			mu.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+	}
+	d.Stop()
+
+	if max > 2 {
+		t.Errorf("%d jobs ran at once with 2 workers", max)
+	}
+}
+
+// blocked fills the queue while its only worker waits on release
+func blocked(t *testing.T, overflow OverflowPolicy) (*Dispatcher, chan struct{}, *[]int, *sync.Mutex) {
+	d := NewDispatcher(Config{Workers: 1, Default: QueueConfig{Limit: 2, Overflow: overflow}})
+	d.Start()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	d.Submit("events", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mu sync.Mutex
+	ran := []int{}
+	for i := 1; i <= 2; i++ {
+		i := i
+		if err := d.Submit("events", func() {
+			mu.Lock()
+			ran = append(ran, i)
+			mu.Unlock()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return d, release, &ran, &mu
+}
+
+func TestDispatcherDropOldest(t *testing.T) {
+	d, release, ran, _ := blocked(t, OverflowDropOldest)
+
+	if err := d.Submit("events", func() { *ran = append(*ran, 3) }); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	d.Stop()
+
+	if diff := deep.Equal(*ran, []int{2, 3}); diff != nil {
+		t.Error(diff)
+	}
+
+	metrics := d.Metrics()["events"]
+	if metrics.Dropped != 1 || metrics.MaxDepth != 2 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestDispatcherReject(t *testing.T) {
+	d, release, ran, _ := blocked(t, OverflowReject)
+
+	if err := d.Submit("events", func() { *ran = append(*ran, 3) }); err != ErrQueueFull {
+		t.Errorf("Submit returned %v, want ErrQueueFull", err)
+	}
+
+	if depth := d.Metrics()["events"].Depth; depth != 2 {
+		t.Errorf("queue depth is %d, want 2", depth)
+	}
+
+	close(release)
+	d.Stop()
+
+	if diff := deep.Equal(*ran, []int{1, 2}); diff != nil {
+		t.Error(diff)
+	}
+
+	if rejected := d.Metrics()["events"].Rejected; rejected != 1 {
+		t.Errorf("rejected %d events, want 1", rejected)
+	}
+}
+
+func TestDispatcherBlock(t *testing.T) {
+	d, release, ran, mu := blocked(t, OverflowBlock)
+
+	submitted := make(chan error)
+	go func() {
+		submitted <- d.Submit("events", func() {
+			mu.Lock()
+			*ran = append(*ran, 3)
+			mu.Unlock()
+		})
+	}()
+
+	select {
+	case <-submitted:
+		t.Fatal("Submit did not wait for room in the queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+	d.Stop()
+
+	if diff := deep.Equal(*ran, []int{1, 2, 3}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestDispatcherQueuesTakeTurns(t *testing.T) {
+	d := NewDispatcher(Config{Workers: 1, Default: QueueConfig{Limit: 10, Overflow: OverflowBlock}})
+
+	ran := []string{}
+	for i := 0; i < 3; i++ {
+		d.Submit("a", func() { ran = append(ran, "a") })
+	}
+	d.Submit("b", func() { ran = append(ran, "b") })
+
+	// the worker starts once everything is queued
+	d.Start()
+	d.Stop()
+
+	if diff := deep.Equal(ran, []string{"a", "b", "a", "a"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(config, DefaultConfig()); diff != nil {
+		t.Error(diff)
+	}
+
+	if err := (Config{Workers: 0, Default: DefaultConfig().Default}).Validate(); err == nil {
+		t.Error("a pool without workers is valid")
+	}
+
+	invalid := DefaultConfig()
+	invalid.Queues = map[string]QueueConfig{"events_api app_mention": {Limit: 10, Overflow: "later"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("an unknown overflow policy is valid")
+	}
+}
+
+func TestDispatcherKeyedJobsRunInOrder(t *testing.T) {
+	d := NewDispatcher(Config{Workers: 4, Default: QueueConfig{Limit: 100, Overflow: OverflowBlock}})
+	d.Start()
+
+	var mu sync.Mutex
+	ran := map[string][]int{}
+	running := map[string]int{}
+	overlap := false
+
+	for i := 0; i < 30; i++ {
+		i := i
+		key := []string{"U1", "U2", "U3"}[i%3]
+		// the events of a user are spread over several queues
+		queue := []string{"events_api app_home_opened", "interactive view_submission"}[i%2]
+
+		d.SubmitKeyed(queue, key, func() {
+			mu.Lock()
+			running[key]++
+			if running[key] > 1 {
+				overlap = true
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running[key]--
+			ran[key] = append(ran[key], i)
+			mu.Unlock()
+		})
+	}
+	d.Stop()
+
+	if overlap {
+		t.Error("jobs sharing a key ran at once")
+	}
+
+	for key, jobs := range ran {
+		for n := 1; n < len(jobs); n++ {
+			if jobs[n] < jobs[n-1] {
+				t.Errorf("jobs of %s ran out of order: %v", key, jobs)
+				break
+			}
+		}
+		if len(jobs) != 10 {
+			t.Errorf("ran %d jobs of %s, want 10", len(jobs), key)
+		}
+	}
+}
+
+func TestDispatcherKeysRunInParallel(t *testing.T) {
+	d := NewDispatcher(Config{Workers: 2, Default: QueueConfig{Limit: 10, Overflow: OverflowBlock}})
+	d.Start()
+	defer d.Stop()
+
+	// U1 waits for U2, they deadlock unless they run in parallel
+	released := make(chan struct{})
+	done := make(chan struct{})
+	d.SubmitKeyed("events", "U1", func() {
+		<-released
+		close(done)
+	})
+	d.SubmitKeyed("events", "U2", func() { close(released) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the job of U2 waited for the job of U1")
+	}
+}
+
+func TestDispatcherDropOldestForgetsKey(t *testing.T) {
+	d := NewDispatcher(Config{Workers: 1, Default: QueueConfig{Limit: 1, Overflow: OverflowDropOldest}})
+
+	ran := []int{}
+	d.SubmitKeyed("events", "U1", func() { ran = append(ran, 1) })
+	d.SubmitKeyed("events", "U1", func() { ran = append(ran, 2) })
+
+	// the dropped job must not block the next jobs of its key
+	d.Start()
+	d.Stop()
+
+	if diff := deep.Equal(ran, []int{2}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestDispatcherShutdown(t *testing.T) {
+	d := NewDispatcher(Config{Workers: 1, Default: QueueConfig{Limit: 10, Overflow: OverflowBlock}})
+	d.Start()
+
+	release := make(chan struct{})
+	d.Submit("events", func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+}
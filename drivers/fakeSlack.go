@@ -0,0 +1,204 @@
+package drivers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// fakeAckTimeout is how long the fake waits for an acknowledgement, like slack
+const fakeAckTimeout = 3 * time.Second
+
+// fakePingInterval keeps the socketmode client from reconnecting during long replays
+const fakePingInterval = 10 * time.Second
+
+// FakeSlack serves the Web API and the Socket Mode connection of a workspace to replay a recording
+// The envelopes are sent on the socket one after the other, each waits for its acknowledgement,
+// every Web API call succeeds and is logged
+type FakeSlack struct {
+	server    *httptest.Server
+	envelopes []json.RawMessage
+
+	mu     sync.Mutex
+	calls  []string
+	replay sync.Once
+	done   chan struct{}
+}
+
+func NewFakeSlack(envelopes []json.RawMessage) *FakeSlack {
+	f := &FakeSlack{
+		envelopes: envelopes,
+		done:      make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", f.openConnection)
+	mux.HandleFunc("/socket", f.socket)
+	mux.HandleFunc("/", f.api)
+	f.server = httptest.NewServer(mux)
+
+	return f
+}
+
+// Client is a socketmode client connected to the fake
+func (f *FakeSlack) Client() *socketmode.Client {
+	api := slack.New(
+		"xoxb-replay",
+		slack.OptionAppLevelToken("xapp-replay"),
+		slack.OptionAPIURL(f.server.URL+"/"),
+	)
+
+	return socketmode.New(api)
+}
+
+// Done is closed once every envelope was sent
+func (f *FakeSlack) Done() <-chan struct{} {
+	return f.done
+}
+
+// Calls lists the Web API calls of the App, e.g. `chat.postMessage channel=C1&text=...`
+func (f *FakeSlack) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string{}, f.calls...)
+}
+
+func (f *FakeSlack) Close() {
+	f.server.CloseClientConnections()
+	f.server.Close()
+}
+
+func (f *FakeSlack) openConnection(w http.ResponseWriter, r *http.Request) {
+	url := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/socket"
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"ok":true,"url":%q}`, url)
+}
+
+// api answers every method, response_url included
+func (f *FakeSlack) api(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	// uploads are only summed up
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		body = []byte(fmt.Sprintf("(%d bytes)", len(body)))
+	}
+	call := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/") + " " + string(body))
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	log.Printf("slack API: %s", call)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func (f *FakeSlack) socket(w http.ResponseWriter, r *http.Request) {
+	// the socketmode client sends the origin of the API
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("ERROR unable to open the fake socket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	acks := make(chan string, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var ack struct {
+				EnvelopeID string `json:"envelope_id"`
+			}
+			if err := conn.ReadJSON(&ack); err != nil {
+				return
+			}
+			select {
+			case acks <- ack.EnvelopeID:
+			default:
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(fakePingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+			}
+		}
+	}()
+
+	conn.WriteJSON(socketmode.Request{Type: socketmode.RequestTypeHello, NumConnections: 1})
+
+	// a reconnection does not replay the recording again
+	f.replay.Do(func() {
+		defer close(f.done)
+
+		for _, envelope := range f.envelopes {
+			if err := f.send(conn, envelope, acks); err != nil {
+				log.Printf("ERROR unable to replay an envelope: %v", err)
+				return
+			}
+		}
+	})
+
+	<-closed
+}
+
+// send writes an envelope on the socket and waits for its acknowledgement
+func (f *FakeSlack) send(conn *websocket.Conn, envelope json.RawMessage, acks chan string) error {
+	var request struct {
+		Type       string `json:"type"`
+		EnvelopeID string `json:"envelope_id"`
+	}
+	json.Unmarshal(envelope, &request)
+
+	// the fake opened its own connection
+	if request.Type == socketmode.RequestTypeHello || request.Type == socketmode.RequestTypeDisconnect {
+		return nil
+	}
+
+	// the answers to response_url come to the fake as well
+	envelope = bytes.ReplaceAll(envelope, []byte(`https:\/\/hooks.slack.com`), []byte(f.server.URL+"/response_url"))
+	envelope = bytes.ReplaceAll(envelope, []byte(`https://hooks.slack.com`), []byte(f.server.URL+"/response_url"))
+
+	if err := conn.WriteMessage(websocket.TextMessage, envelope); err != nil {
+		return err
+	}
+
+	if request.EnvelopeID == "" {
+		return nil
+	}
+
+	timeout := time.After(fakeAckTimeout)
+	for {
+		select {
+		case id := <-acks:
+			if id == request.EnvelopeID {
+				return nil
+			}
+		case <-timeout:
+			log.Printf("WARNING %s was not acknowledged within %v", request.EnvelopeID, fakeAckTimeout)
+			return nil
+		}
+	}
+}
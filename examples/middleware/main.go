@@ -4,6 +4,7 @@ import (
 	"log"
 	"math/rand"
 	"time"
+	"xnok/slack-go-demo/dispatch"
 )
 
 // type used to enumerate events
@@ -49,6 +50,8 @@ type EventHandler struct {
 	Events chan Event
 	// hold the registedred event functionss
 	EventMap map[EventType][]func(Event)
+	// run the event functions on a bounded worker pool
+	Dispatcher *dispatch.Dispatcher
 }
 
 func NewEventHandler() *EventHandler {
@@ -56,8 +59,9 @@ func NewEventHandler() *EventHandler {
 	events := make(chan Event)
 
 	return &EventHandler{
-		Events:   events,
-		EventMap: eventMap,
+		Events:     events,
+		EventMap:   eventMap,
+		Dispatcher: dispatch.NewDispatcher(dispatch.DefaultConfig()),
 	}
 }
 
@@ -67,13 +71,19 @@ func (h *EventHandler) Handle(e EventType, f func(Event)) {
 }
 
 func (h *EventHandler) EventDispatcher() {
+	h.Dispatcher.Start()
+	defer h.Dispatcher.Stop()
+
 	for evt := range h.Events {
 		log.Printf("event recieved: %v", evt)
 		if handlers, ok := h.EventMap[evt.Type]; ok {
 			// If we registered an event
 			for _, f := range handlers {
-				// exacute function as goroutine
-				go f(evt)
+				f, evt := f, evt
+				// queue the function for the worker pool
+				if err := h.Dispatcher.Submit(string(evt.Type), func() { f(evt) }); err != nil {
+					log.Printf("event dropped: %v", err)
+				}
 			}
 		}
 	}
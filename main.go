@@ -2,8 +2,11 @@ package main
 
 import (
 	"os"
-	"xnok/slack-go-demo/controllers"
+	"os/signal"
+	"syscall"
 	"xnok/slack-go-demo/drivers"
+	"xnok/slack-go-demo/middleware"
+	"xnok/slack-go-demo/services"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
@@ -14,6 +17,11 @@ import (
 func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
+	// go run . replay recording.jsonl
+	if len(os.Args) == 3 && os.Args[1] == "replay" {
+		os.Exit(replay(os.Args[2]))
+	}
+
 	err := godotenv.Load("./test_slack.env")
 	if err != nil {
 		log.Fatal().Msg("Error loading .env file")
@@ -29,18 +37,61 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Inject Deps in router
-	socketmodeHandler := socketmode.NewsSocketmodeHandler(client)
+	// Every incoming event is recorded when RECORDING_FILE is set
+	first := []middleware.Middleware{}
+	recording := os.Getenv("RECORDING_FILE")
+	var recordingFile *os.File
+	if recording != "" {
+		rules, err := middleware.LoadRedactionRules(os.Getenv("RECORDING_REDACTION_FILE"))
+		if err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msg("Unable to load the redaction rules")
+
+			os.Exit(1)
+		}
 
-	// This if for Separate articles and demos. You can run there separatly or all together
+		recordingFile, err = os.OpenFile(recording, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Error().
+				Str("error", err.Error()).
+				Msg("Unable to open the recording")
+
+			os.Exit(1)
+		}
+
+		recorder := middleware.NewRecorder(recordingFile, services.SystemClock{}, rules)
+		first = append(first, recorder.Record)
+	}
 
-	// Build a Slack App Home in Golang Using Socket Mode
-	controllers.NewAppHomeController(socketmodeHandler)
-	// Properly Welcome Users in Slack with Golang using Socket Mode
-	controllers.NewGreetingController(socketmodeHandler)
-	// Build Slack Slash Command in Golang Using Socket Mode
-	controllers.NewSlashCommandController(socketmodeHandler)
+	app := newApp(client, first...)
 
-	socketmodeHandler.RunEventLoop()
+	if recordingFile != nil {
+		// the events without handler are recorded as well
+		app.router.HandleDefault(func(evt *socketmode.Event, clt *socketmode.Client) {})
+		app.flushers = append(app.flushers, flusher{"recording", recordingFile.Sync})
+	}
+
+	// Stop on SIGINT and SIGTERM
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	lost := make(chan struct{})
+	go func() {
+		app.router.RunEventLoop()
+		close(lost)
+	}()
+
+	select {
+	case sig := <-signals:
+		log.Info().
+			Str("signal", sig.String()).
+			Dur("grace_period", app.grace).
+			Msg("Shutting down")
+	case <-lost:
+		log.Error().Msg("The connection to slack was lost")
+		os.Exit(exitFailure)
+	}
 
+	os.Exit(app.shutdown())
 }
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"log"
+	"sync"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack/socketmode"
+)
+
+// AckDeadline is how long slack waits for an acknowledgement before retrying
+const AckDeadline = 3 * time.Second
+
+// DefaultAutoAckAfter leaves some room to send the acknowledgement before the deadline
+const DefaultAutoAckAfter = 2500 * time.Millisecond
+
+// ackRetention is how long an acknowledged request is kept to detect double acks
+const ackRetention = time.Minute
+
+// AckMetrics counts how the requests were acknowledged
+type AckMetrics struct {
+	Tracked   int
+	Acked     int
+	AutoAcked int
+	// Late acks were sent after the AckDeadline
+	Late     int
+	Double   int
+	MaxDelay time.Duration
+}
+
+// ackEntry is a request waiting for its acknowledgement
+type ackEntry struct {
+	manager  *AckManager
+	request  socketmode.Request
+	client   *socketmode.Client
+	received time.Time
+	acked    bool
+	timer    services.Timer
+}
+
+// registry holds the tracked requests by envelope ID
+// so Ack finds them whatever the handler acknowledging them
+var registry = struct {
+	sync.Mutex
+	entries map[string]*ackEntry
+}{entries: make(map[string]*ackEntry)}
+
+// AckManager makes sure every request is acknowledged once before the deadline
+// Handlers acknowledge with Ack, the request is acknowledged without payload
+// after autoAckAfter if none of them did
+type AckManager struct {
+	clock        services.Clock
+	autoAckAfter time.Duration
+
+	mu      sync.Mutex
+	metrics AckMetrics
+}
+
+func NewAckManager(clock services.Clock, autoAckAfter time.Duration) *AckManager {
+	return &AckManager{
+		clock:        clock,
+		autoAckAfter: autoAckAfter,
+	}
+}
+
+// Track is a middleware registering the request of every event
+// it should run first so the other middlewares acknowledge through it
+func (m *AckManager) Track(next Handler) Handler {
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		if evt.Request != nil && evt.Request.EnvelopeID != "" {
+			m.track(*evt.Request, clt)
+		}
+
+		next(evt, clt)
+	}
+}
+
+// Metrics returns a copy of the counters
+func (m *AckManager) Metrics() AckMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.metrics
+}
+
+func (m *AckManager) track(request socketmode.Request, clt *socketmode.Client) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	prune()
+
+	// Several handlers may receive the same event
+	if _, ok := registry.entries[request.EnvelopeID]; ok {
+		return
+	}
+
+	entry := &ackEntry{
+		manager:  m,
+		request:  request,
+		client:   clt,
+		received: m.clock.Now(),
+	}
+	registry.entries[request.EnvelopeID] = entry
+
+	entry.timer = m.clock.AfterFunc(m.autoAckAfter, func() {
+		m.autoAck(request.EnvelopeID)
+	})
+
+	m.mu.Lock()
+	m.metrics.Tracked++
+	m.mu.Unlock()
+}
+
+// autoAck acknowledges a request no handler acknowledged in time
+func (m *AckManager) autoAck(envelopeID string) {
+	registry.Lock()
+	entry, ok := registry.entries[envelopeID]
+	if !ok || entry.acked {
+		registry.Unlock()
+		return
+	}
+	entry.acked = true
+	registry.Unlock()
+
+	log.Printf("WARNING request %s was not acknowledged by its handlers, acknowledging it", envelopeID)
+
+	m.mu.Lock()
+	m.metrics.AutoAcked++
+	m.mu.Unlock()
+
+	if entry.client != nil {
+		entry.client.Ack(entry.request)
+	}
+}
+
+func (m *AckManager) acked(envelopeID string, delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.Acked++
+	if delay > m.metrics.MaxDelay {
+		m.metrics.MaxDelay = delay
+	}
+
+	if delay > AckDeadline {
+		m.metrics.Late++
+		log.Printf("WARNING request %s acknowledged after %v, slack may retry it", envelopeID, delay)
+	}
+}
+
+func (m *AckManager) double(envelopeID string) {
+	m.mu.Lock()
+	m.metrics.Double++
+	m.mu.Unlock()
+
+	log.Printf("WARNING request %s is already acknowledged, ignoring the new acknowledgement", envelopeID)
+}
+
+// prune forgets the requests acknowledged a while ago, the registry must be locked
+func prune() {
+	for id, entry := range registry.entries {
+		if entry.acked && entry.manager.clock.Now().Sub(entry.received) > ackRetention {
+			delete(registry.entries, id)
+		}
+	}
+}
+
+// Ack acknowledges the request of an event, the payload is optional
+// e.g. the options of a select or the response_action of a view submission
+// Requests that are not tracked by an AckManager are acknowledged as is
+func Ack(evt *socketmode.Event, clt *socketmode.Client, payload ...interface{}) {
+	if evt.Request == nil || clt == nil {
+		return
+	}
+
+	registry.Lock()
+	entry, ok := registry.entries[evt.Request.EnvelopeID]
+	if ok && entry.acked {
+		registry.Unlock()
+		entry.manager.double(evt.Request.EnvelopeID)
+		return
+	}
+	if ok {
+		entry.acked = true
+		entry.timer.Stop()
+	}
+	registry.Unlock()
+
+	if ok {
+		entry.manager.acked(evt.Request.EnvelopeID, entry.manager.clock.Now().Sub(entry.received))
+	}
+
+	clt.Ack(*evt.Request, payload...)
+}
+
+// Acked tells if the request of a tracked event is acknowledged
+func Acked(evt *socketmode.Event) bool {
+	if evt.Request == nil {
+		return false
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	entry, ok := registry.entries[evt.Request.EnvelopeID]
+	return ok && entry.acked
+}
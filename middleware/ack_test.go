@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestAckManager(t *testing.T) {
+	clock := services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	acks := NewAckManager(clock, DefaultAutoAckAfter)
+	soccketClient := socketmode.New(slack.New("ABCD"))
+
+	event := func(id string) *socketmode.Event {
+		return &socketmode.Event{
+			Type:    socketmode.EventTypeSlashCommand,
+			Request: &socketmode.Request{EnvelopeID: id},
+		}
+	}
+
+	noop := func(evt *socketmode.Event, clt *socketmode.Client) {}
+
+	// The handler acknowledges twice, the second one is ignored
+	acks.Track(AutoAck(func(evt *socketmode.Event, clt *socketmode.Client) {
+		Ack(evt, clt)
+	}))(event("ack-twice"), soccketClient)
+
+	if !Acked(event("ack-twice")) {
+		t.Error("Acked() = false after Ack")
+	}
+
+	// Two handlers receive the same event, none acknowledges it
+	acks.Track(noop)(event("forgotten"), soccketClient)
+	acks.Track(noop)(event("forgotten"), soccketClient)
+
+	if Acked(event("forgotten")) {
+		t.Error("Acked() = true before the deadline")
+	}
+
+	clock.Advance(DefaultAutoAckAfter)
+
+	if !Acked(event("forgotten")) {
+		t.Error("the request was not acknowledged before the deadline")
+	}
+
+	got := acks.Metrics()
+	want := AckMetrics{Tracked: 2, Acked: 1, AutoAcked: 1, Double: 1}
+	if got != want {
+		t.Errorf("Metrics() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAckManager_Late(t *testing.T) {
+	clock := services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	// The auto ack comes too late to measure a late handler
+	acks := NewAckManager(clock, time.Minute)
+	soccketClient := socketmode.New(slack.New("ABCD"))
+
+	evt := &socketmode.Event{
+		Type:    socketmode.EventTypeSlashCommand,
+		Request: &socketmode.Request{EnvelopeID: "late"},
+	}
+
+	acks.Track(func(evt *socketmode.Event, clt *socketmode.Client) {
+		clock.Advance(4 * time.Second)
+		Ack(evt, clt)
+	})(evt, soccketClient)
+
+	got := acks.Metrics()
+	if got.Late != 1 || got.MaxDelay != 4*time.Second {
+		t.Errorf("Metrics() = %+v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log"
+	"time"
+
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Logging logs every event reaching a handler
+func Logging(next Handler) Handler {
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		log.Printf("event received: %s", Describe(evt))
+
+		next(evt, clt)
+	}
+}
+
+// Timing logs how long a handler took
+// handlers slower than slow are logged as a warning, every handler is logged when slow is 0
+func Timing(slow time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			start := time.Now()
+
+			// deferred so panicking handlers are timed as well
+			defer func() {
+				elapsed := time.Since(start)
+				if elapsed >= slow && slow > 0 {
+					log.Printf("WARNING slow handler for %s: %v", Describe(evt), elapsed)
+				} else if slow == 0 {
+					log.Printf("event handled: %s in %v", Describe(evt), elapsed)
+				}
+			}()
+
+			next(evt, clt)
+		}
+	}
+}
+
+// AutoAck acknowledges the request before the handler runs
+// it must not be used by handlers answering with a payload, e.g. options loads
+func AutoAck(next Handler) Handler {
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		// Make sure to respond to the server to avoid an error
+		Ack(evt, clt)
+
+		next(evt, clt)
+	}
+}
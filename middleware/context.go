@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// ContextHandler is a handler bounded by a context
+// the context is cancelled when the App shuts down or when the route times out
+// and carries the correlation ID, the user and the logger of the request
+type ContextHandler func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error
+
+// UserInfoGetter resolves the user who triggered an event, e.g. the services.Directory
+type UserInfoGetter interface {
+	GetUserInfo(user string) (*slack.User, error)
+}
+
+type contextKey int
+
+const (
+	correlationIDKey contextKey = iota
+	userKey
+	loggerKey
+	stepsKey
+)
+
+// resolvedUser looks the user up the first time a handler needs it
+type resolvedUser struct {
+	id    string
+	users UserInfoGetter
+
+	once sync.Once
+	user *slack.User
+	err  error
+}
+
+func (u *resolvedUser) get() (*slack.User, error) {
+	u.once.Do(func() {
+		if u.users == nil {
+			u.user = &slack.User{ID: u.id}
+			return
+		}
+		u.user, u.err = u.users.GetUserInfo(u.id)
+	})
+
+	return u.user, u.err
+}
+
+// CorrelationID identifies the request in the logs
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey).(string)
+	return id
+}
+
+// UserID is the user who triggered the event, empty for events without user
+func UserID(ctx context.Context) string {
+	if u, ok := ctx.Value(userKey).(*resolvedUser); ok {
+		return u.id
+	}
+
+	return ""
+}
+
+// User resolves the user who triggered the event
+func User(ctx context.Context) (*slack.User, error) {
+	u, ok := ctx.Value(userKey).(*resolvedUser)
+	if !ok || u.id == "" {
+		return nil, nil
+	}
+
+	return u.get()
+}
+
+// Logger prefixes the logs with the correlation ID of the request
+func Logger(ctx context.Context) *log.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*log.Logger); ok {
+		return logger
+	}
+
+	return log.Default()
+}
+
+// NewRequestContext carries the values of a request on top of parent
+// the correlation ID is the one Recover gave to the event, if any
+func NewRequestContext(parent context.Context, evt *socketmode.Event, users UserInfoGetter) context.Context {
+	id := EventCorrelationID(evt)
+
+	ctx := context.WithValue(parent, correlationIDKey, id)
+	ctx = context.WithValue(ctx, userKey, &resolvedUser{id: UserKey(evt), users: users})
+	ctx = context.WithValue(ctx, loggerKey, log.New(log.Writer(), "(ref: "+id+") ", log.Flags()|log.Lmsgprefix))
+	ctx = context.WithValue(ctx, stepsKey, &steps{done: make(map[string]interface{})})
+
+	return ctx
+}
+
+// WithContext adapts a ContextHandler to the routes of the Router
+// the context times out after timeout, 0 does not limit the handler
+// A handler failing with a transient error runs again following the Retry policy, see Step to not repeat what succeeded,
+// its last error is logged with its correlation ID and the event is dead lettered
+func (r *Router) WithContext(h ContextHandler, timeout time.Duration) Handler {
+	route := r.register(h, timeout)
+
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		ctx := NewRequestContext(r.baseContext(), evt, r.Users)
+
+		attempts, err := r.run(ctx, route, evt, clt)
+		if err != nil {
+			r.deadLetter(ctx, route, evt, attempts, err)
+		}
+	}
+}
+
+// run calls the handler until it succeeds, fails with an error that is not transient or runs out of attempts
+func (r *Router) run(ctx context.Context, route contextRoute, evt *socketmode.Event, clt *socketmode.Client) (int, error) {
+	logger := Logger(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err := r.attempt(ctx, route, evt, clt)
+		if err == nil {
+			return attempt, nil
+		}
+
+		if !r.Retry.Retry(attempt, err) {
+			logger.Printf("ERROR %s failed after %d attempt(s): %v", Describe(evt), attempt, err)
+			return attempt, err
+		}
+
+		backoff := r.Retry.Backoff(attempt, err)
+		logger.Printf("WARNING %s failed, retrying in %v: %v", Describe(evt), backoff, err)
+
+		// the App shuts down
+		if r.wait(ctx, backoff) != nil {
+			logger.Printf("ERROR %s failed after %d attempt(s): %v", Describe(evt), attempt, err)
+			return attempt, err
+		}
+	}
+}
+
+// attempt calls the handler once within the timeout of its route
+func (r *Router) attempt(ctx context.Context, route contextRoute, evt *socketmode.Event, clt *socketmode.Client) error {
+	if route.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, route.timeout)
+		defer cancel()
+	}
+
+	err := route.handler(ctx, evt, clt)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		Logger(ctx).Printf("WARNING %s exceeded its timeout of %v", Describe(evt), route.timeout)
+	}
+
+	return err
+}
+
+// wait returns once d elapsed or with the error of ctx when it is done first
+func (r *Router) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	elapsed := make(chan struct{})
+	timer := r.clock().AfterFunc(d, func() { close(elapsed) })
+	defer timer.Stop()
+
+	select {
+	case <-elapsed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Adapt lets a handler without context be used as a ContextHandler during the migration
+func Adapt(h Handler) ContextHandler {
+	return func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		h(evt, clt)
+		return nil
+	}
+}
+
+func (r *Router) baseContext() context.Context {
+	if r.BaseContext != nil {
+		return r.BaseContext
+	}
+
+	return context.Background()
+}
+
+func (r *Router) clock() services.Clock {
+	if r.Clock != nil {
+		return r.Clock
+	}
+
+	return services.SystemClock{}
+}
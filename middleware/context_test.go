@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+type fakeUsers map[string]*slack.User
+
+func (f fakeUsers) GetUserInfo(user string) (*slack.User, error) {
+	if u, ok := f[user]; ok {
+		return u, nil
+	}
+
+	return nil, errors.New("user_not_found")
+}
+
+func TestRouter_WithContext(t *testing.T) {
+	router := NewRouter(socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD"))))
+	router.Users = fakeUsers{"U1": {ID: "U1", Name: "elon"}}
+
+	evt := &socketmode.Event{
+		Type: socketmode.EventTypeSlashCommand,
+		Data: slack.SlashCommand{Command: "/rocket", UserID: "U1"},
+	}
+
+	var got struct {
+		ref      string
+		user     *slack.User
+		deadline bool
+	}
+	router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		got.ref = CorrelationID(ctx)
+		got.user, _ = User(ctx)
+		_, got.deadline = ctx.Deadline()
+		return nil
+	}, time.Second)(evt, nil)
+
+	if len(got.ref) != 8 {
+		t.Errorf("CorrelationID() = %q", got.ref)
+	}
+	if got.user == nil || got.user.Name != "elon" {
+		t.Errorf("User() = %+v", got.user)
+	}
+	if !got.deadline {
+		t.Error("the context of the route has no deadline")
+	}
+}
+
+func TestRouter_WithContextCancelled(t *testing.T) {
+	router := NewRouter(socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD"))))
+
+	// The App is shutting down
+	base, cancel := context.WithCancel(context.Background())
+	router.BaseContext = base
+	cancel()
+
+	var err error
+	router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		err = ctx.Err()
+		return err
+	}, 0)(&socketmode.Event{}, nil)
+
+	if err != context.Canceled {
+		t.Errorf("ctx.Err() = %v, want context.Canceled", err)
+	}
+}
+
+func TestRouter_WithContextTimeout(t *testing.T) {
+	router := NewRouter(socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD"))))
+
+	var err error
+	router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		<-ctx.Done()
+		err = ctx.Err()
+		return err
+	}, 10*time.Millisecond)(&socketmode.Event{}, nil)
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("ctx.Err() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	called := false
+	h := Adapt(func(evt *socketmode.Event, clt *socketmode.Client) {
+		called = true
+	})
+
+	if err := h(context.Background(), &socketmode.Event{}, nil); err != nil || !called {
+		t.Errorf("Adapt() returned %v, called %v", err, called)
+	}
+}
+
+func TestUser_WithoutResolver(t *testing.T) {
+	ctx := NewRequestContext(context.Background(), &socketmode.Event{Data: slack.SlashCommand{UserID: "U2"}}, nil)
+
+	user, err := User(ctx)
+	if err != nil || user.ID != "U2" || UserID(ctx) != "U2" {
+		t.Errorf("User() = %+v, %v", user, err)
+	}
+
+	if Logger(context.Background()) == nil {
+		t.Error("Logger() is nil without request context")
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+var ErrNoDeadLetterStore = errors.New("the failed events are not kept")
+
+// contextRoute is a ContextHandler registered with WithContext, found by name to replay its dead letters
+type contextRoute struct {
+	name    string
+	handler ContextHandler
+	timeout time.Duration
+}
+
+// register names a ContextHandler after its function
+// e.g. `controllers.(*AppHomeController).publishHomeTabView`
+func (r *Router) register(h ContextHandler, timeout time.Duration) contextRoute {
+	route := contextRoute{name: handlerName(h), handler: h, timeout: timeout}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.routes == nil {
+		r.routes = make(map[string]contextRoute)
+	}
+	r.routes[route.name] = route
+
+	return route
+}
+
+func handlerName(h ContextHandler) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+
+	// without the path of the package nor the suffix of the method values
+	name = name[strings.LastIndex(name, "/")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// deadLetter keeps the event whose handler failed so it can be inspected and replayed
+func (r *Router) deadLetter(ctx context.Context, route contextRoute, evt *socketmode.Event, attempts int, failure error) {
+	if r.DeadLetters == nil {
+		return
+	}
+
+	logger := Logger(ctx)
+
+	envelope, err := envelopeOf(evt)
+	if err != nil {
+		logger.Printf("ERROR unable to read the envelope of %s: %v", Describe(evt), err)
+	}
+
+	letter := services.DeadLetter{
+		ID:       CorrelationID(ctx),
+		Time:     r.clock().Now(),
+		Event:    Describe(evt),
+		Envelope: envelope,
+		Handler:  route.name,
+		Error:    failure.Error(),
+		Attempts: attempts,
+	}
+
+	if err := r.DeadLetters.Put(letter); err != nil {
+		logger.Printf("ERROR unable to keep the dead letter of %s: %v", Describe(evt), err)
+		return
+	}
+
+	logger.Printf("WARNING %s is dead lettered as %s", Describe(evt), letter.ID)
+}
+
+// Replay runs the handler of a dead letter again with its event
+// the dead letter is deleted once the handler succeeds, its error and attempts are updated otherwise
+func (r *Router) Replay(id string, clt *socketmode.Client) error {
+	if r.DeadLetters == nil {
+		return ErrNoDeadLetterStore
+	}
+
+	letter, err := r.DeadLetters.Get(id)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	route, ok := r.routes[letter.Handler]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("the handler %s is not registered", letter.Handler)
+	}
+
+	evt, err := ParseEnvelope(letter.Envelope)
+	if err != nil {
+		return err
+	}
+
+	ctx := NewRequestContext(r.baseContext(), evt, r.Users)
+	Logger(ctx).Printf("replaying the dead letter %s of %s", id, letter.Event)
+
+	attempts, err := r.run(ctx, route, evt, clt)
+	if err != nil {
+		letter.Attempts += attempts
+		letter.Error = err.Error()
+		r.DeadLetters.Put(letter)
+
+		return err
+	}
+
+	return r.DeadLetters.Delete(id)
+}
+
+// ParseEnvelope rebuilds the event of a message received on the socket, like the socketmode client
+func ParseEnvelope(envelope json.RawMessage) (*socketmode.Event, error) {
+	if len(envelope) == 0 {
+		return nil, errors.New("the event has no envelope")
+	}
+
+	req := &socketmode.Request{}
+	if err := json.Unmarshal(envelope, req); err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case socketmode.RequestTypeEventsAPI:
+		data, err := slackevents.ParseEvent(req.Payload, slackevents.OptionNoVerifyToken())
+		if err != nil {
+			return nil, fmt.Errorf("parsing Events API event: %w", err)
+		}
+
+		return &socketmode.Event{Type: socketmode.EventTypeEventsAPI, Data: data, Request: req}, nil
+	case socketmode.RequestTypeSlashCommands:
+		var data slack.SlashCommand
+		if err := json.Unmarshal(req.Payload, &data); err != nil {
+			return nil, fmt.Errorf("parsing slash command: %w", err)
+		}
+
+		return &socketmode.Event{Type: socketmode.EventTypeSlashCommand, Data: data, Request: req}, nil
+	case socketmode.RequestTypeInteractive:
+		var data slack.InteractionCallback
+		if err := json.Unmarshal(req.Payload, &data); err != nil {
+			return nil, fmt.Errorf("parsing interaction callback: %w", err)
+		}
+
+		return &socketmode.Event{Type: socketmode.EventTypeInteractive, Data: data, Request: req}, nil
+	}
+
+	return nil, fmt.Errorf("the %q requests cannot be replayed", req.Type)
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+var appHomeEnvelope = json.RawMessage(`{"envelope_id":"e1","type":"events_api","payload":{"type":"event_callback","event_id":"Ev1","event":{"type":"app_home_opened","user":"U1","tab":"home"}}}`)
+
+func TestRouter_DeadLetter(t *testing.T) {
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+	router := NewRouter(socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD"))))
+	router.Retry = RetryPolicy{Attempts: 3, Multiplier: 1}
+	router.DeadLetters = services.NewMemoryDeadLetterStore(services.DefaultDeadLetterLimit)
+	router.Clock = services.NewFakeClock(now)
+
+	evt, err := ParseEnvelope(appHomeEnvelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slack is unavailable, then the user is unknown
+	failures := []error{errors.New("service_unavailable"), errors.New("user_not_found")}
+	var ref string
+	attempts := 0
+	router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		ref = CorrelationID(ctx)
+		attempts++
+		return failures[attempts-1]
+	}, 0)(evt, nil)
+
+	if attempts != 2 {
+		t.Errorf("the handler ran %d times, want 2", attempts)
+	}
+
+	letters, _ := router.DeadLetters.List()
+	if len(letters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(letters))
+	}
+
+	got := letters[0]
+	got.Envelope = nil
+	want := services.DeadLetter{
+		ID:       ref,
+		Time:     now,
+		Event:    "events_api app_home_opened",
+		Handler:  "middleware.TestRouter_DeadLetter.func1",
+		Error:    "user_not_found",
+		Attempts: 2,
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+
+	// the envelope rebuilds the event
+	replayed, err := ParseEnvelope(letters[0].Envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(replayed.Request.EnvelopeID, "e1"); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestRouter_Replay(t *testing.T) {
+	router := NewRouter(socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD"))))
+	router.DeadLetters = services.NewMemoryDeadLetterStore(services.DefaultDeadLetterLimit)
+
+	evt, _ := ParseEnvelope(appHomeEnvelope)
+
+	var failure error = errors.New("internal_error")
+	users := []string{}
+	router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		inner := evt.Data.(slackevents.EventsAPIEvent).InnerEvent.Data.(*slackevents.AppHomeOpenedEvent)
+		users = append(users, inner.User)
+		return failure
+	}, 0)(evt, nil)
+
+	letters, _ := router.DeadLetters.List()
+	if len(letters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(letters))
+	}
+	id := letters[0].ID
+
+	// slack is still unavailable
+	if err := router.Replay(id, nil); err != failure {
+		t.Errorf("Replay() = %v, want %v", err, failure)
+	}
+	letter, _ := router.DeadLetters.Get(id)
+	if letter.Attempts != 2 {
+		t.Errorf("%d attempts, want 2", letter.Attempts)
+	}
+
+	failure = nil
+	if err := router.Replay(id, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := router.DeadLetters.Get(id); err != services.ErrDeadLetterNotFound {
+		t.Errorf("the replayed event is still dead lettered: %v", err)
+	}
+	if diff := deep.Equal(users, []string{"U1", "U1", "U1"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if err := router.Replay("unknown", nil); err != services.ErrDeadLetterNotFound {
+		t.Errorf("Replay(unknown) = %v", err)
+	}
+}
+
+func TestParseEnvelope(t *testing.T) {
+	evt, err := ParseEnvelope(json.RawMessage(`{"envelope_id":"e2","type":"slash_commands","payload":{"command":"/rocket","text":"10"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(evt.Data, slack.SlashCommand{Command: "/rocket", Text: "10"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if _, err := ParseEnvelope(json.RawMessage(`{"type":"hello"}`)); err == nil {
+		t.Error("a hello is replayed")
+	}
+}
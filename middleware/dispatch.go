@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log"
+	"xnok/slack-go-demo/dispatch"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// EventType names the queue of an event in the dispatcher
+// e.g. `events_api app_home_opened`, `interactive block_actions`, `slash_commands /rocket`
+func EventType(evt *socketmode.Event) string {
+	switch data := evt.Data.(type) {
+	case slack.SlashCommand:
+		return string(evt.Type) + " " + data.Command
+	case slack.InteractionCallback:
+		return string(evt.Type) + " " + string(data.Type)
+	case slackevents.EventsAPIEvent:
+		return string(evt.Type) + " " + data.InnerEvent.Type
+	}
+
+	return string(evt.Type)
+}
+
+// Dispatch runs the handlers on the worker pool of the dispatcher
+// it should run after AckManager.Track so queued requests are still acknowledged in time
+// Register it with Router.Dispatch: inside the goroutine the SocketmodeHandler starts for each handler
+// the events are neither submitted in order nor bounded
+func Dispatch(d *dispatch.Dispatcher) Middleware {
+	return DispatchBy(d, nil)
+}
+
+// DispatchBy runs the handlers of events sharing a key one after the other
+// e.g. DispatchBy(d, UserKey) handles the events of a user in order and different users in parallel
+func DispatchBy(d *dispatch.Dispatcher, key KeyFunc) Middleware {
+	return func(next Handler) Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			shard := ""
+			if key != nil {
+				shard = key(evt)
+			}
+
+			err := d.SubmitKeyed(EventType(evt), shard, func() {
+				next(evt, clt)
+			})
+
+			if err != nil {
+				log.Printf("ERROR %s was not handled: %v", Describe(evt), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Drain stops handling new events once the App is shutting down
+// and waits for the handlers in flight
+type Drain struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+func NewDrain() *Drain {
+	return &Drain{}
+}
+
+// Gate is a middleware counting the handlers in flight
+// it should run first: the events received after Close are not acknowledged so slack delivers them again
+func (d *Drain) Gate(next Handler) Handler {
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			log.Printf("WARNING shutting down, leaving %s to slack", Describe(evt))
+			return
+		}
+		d.inflight.Add(1)
+		d.mu.Unlock()
+
+		defer d.inflight.Done()
+
+		next(evt, clt)
+	}
+}
+
+// Close refuses the new events
+func (d *Drain) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+}
+
+// Wait returns once the handlers in flight are done or when ctx is done
+// it should be called after Close
+func (d *Drain) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
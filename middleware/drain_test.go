@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestDrain(t *testing.T) {
+	drain := NewDrain()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handled := 0
+	h := drain.Gate(func(evt *socketmode.Event, clt *socketmode.Client) {
+		handled++
+		if handled == 1 {
+			close(started)
+			<-release
+		}
+	})
+
+	go h(&socketmode.Event{}, nil)
+	<-started
+
+	drain.Close()
+
+	// The events received while shutting down are left to slack
+	h(&socketmode.Event{}, nil)
+
+	// The handler in flight is still running
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := drain.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := drain.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+
+	if handled != 1 {
+		t.Errorf("handled %d events, want 1", handled)
+	}
+}
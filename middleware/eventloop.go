@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"xnok/slack-go-demo/dispatch"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Dispatch runs the routes registered afterwards on the worker pool of the dispatcher
+// The events are submitted by the event loop in the order they are received, before any goroutine is started,
+// so the events sharing a key are handled in order and a full queue slows the reading of the socket down
+// The global middlewares added before it run on the event loop, e.g. AckManager.Track, they should be quick
+func (r *Router) Dispatch(d *dispatch.Dispatcher, key KeyFunc) {
+	r.dispatcher = d
+	r.Use(DispatchBy(d, key))
+}
+
+// RunEventLoop receives the events via the socket
+func (r *Router) RunEventLoop() {
+	// Without dispatcher the SocketmodeHandler starts a goroutine per handler
+	if r.dispatcher == nil {
+		r.EventHandler.RunEventLoop()
+		return
+	}
+
+	go r.runEventLoop()
+
+	r.Client().Run()
+}
+
+// runEventLoop calls the handlers of each event one after the other, they only submit it to the dispatcher
+func (r *Router) runEventLoop() {
+	for evt := range r.Client().Events {
+		evt := evt
+		for _, f := range r.handlers(&evt) {
+			f(&evt, r.Client())
+		}
+	}
+}
+
+// handlers finds the handlers of an event like the SocketmodeHandler does
+// by socketmode event type, then by interaction type and ActionID, Events API type or slash command
+func (r *Router) handlers(evt *socketmode.Event) []socketmode.SocketmodeHandlerFunc {
+	h := r.EventHandler
+
+	matched := append([]socketmode.SocketmodeHandlerFunc{}, h.EventMap[evt.Type]...)
+
+	switch data := evt.Data.(type) {
+	case slack.InteractionCallback:
+		matched = append(matched, h.InteractionEventMap[data.Type]...)
+		for _, action := range data.ActionCallback.BlockActions {
+			matched = append(matched, h.InteractionBlockActionEventMap[action.ActionID]...)
+		}
+	case slackevents.EventsAPIEvent:
+		matched = append(matched, h.EventApiMap[slackevents.EventAPIType(data.InnerEvent.Type)]...)
+	case slack.SlashCommand:
+		matched = append(matched, h.SlashCommandMap[data.Command]...)
+	}
+
+	if len(matched) == 0 && h.Default != nil {
+		matched = append(matched, h.Default)
+	}
+
+	return matched
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/dispatch"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestRouterDispatch(t *testing.T) {
+	client := socketmode.New(slack.New("ABCD"))
+	eventhandler := socketmode.NewsSocketmodeHandler(client)
+
+	d := dispatch.NewDispatcher(dispatch.Config{
+		Workers: 4,
+		Default: dispatch.QueueConfig{Limit: 100, Overflow: dispatch.OverflowBlock},
+	})
+	d.Start()
+
+	router := NewRouter(eventhandler)
+	router.Dispatch(d, UserKey)
+
+	const events = 20
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	handled := map[string][]string{}
+	router.HandleSlashCommand("/rocket", func(evt *socketmode.Event, clt *socketmode.Client) {
+		defer wg.Done()
+		command := evt.Data.(slack.SlashCommand)
+
+		// The first events of a user are the slowest
+		var n int
+		fmt.Sscan(command.Text, &n)
+		time.Sleep(time.Duration(events-n) * time.Millisecond)
+
+		mu.Lock()
+		handled[command.UserID] = append(handled[command.UserID], command.Text)
+		mu.Unlock()
+	})
+
+	// The events are received by the socketmode client
+	want := map[string][]string{}
+	wg.Add(events)
+	for i := 0; i < events; i++ {
+		user := fmt.Sprintf("U%d", i%2)
+		text := fmt.Sprint(i)
+		want[user] = append(want[user], text)
+
+		client.Events <- socketmode.Event{
+			Type: socketmode.EventTypeSlashCommand,
+			Data: slack.SlashCommand{Command: "/rocket", UserID: user, Text: text},
+		}
+	}
+	go router.runEventLoop()
+
+	wg.Wait()
+	d.Stop()
+
+	// The events of a user are handled in the order they were received
+	if diff := deep.Equal(handled, want); diff != nil {
+		t.Error(diff)
+	}
+
+	if processed := d.Metrics()["slash_commands /rocket"].Processed; processed != events {
+		t.Errorf("processed %d events, want %d", processed, events)
+	}
+}
+
+func TestRouterHandlers(t *testing.T) {
+	eventhandler := socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD")))
+	router := NewRouter(eventhandler)
+
+	calls := []string{}
+	handler := func(name string) Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			calls = append(calls, name)
+		}
+	}
+	router.Handle(socketmode.EventTypeInteractive, handler("interactive"))
+	router.HandleInteraction(slack.InteractionTypeBlockActions, handler("block_actions"))
+	router.HandleInteractionBlockAction("rocket_launch_approved", handler("rocket_launch_approved"))
+	router.HandleDefault(handler("default"))
+
+	// The handlers match like the SocketmodeHandler
+	for _, f := range router.handlers(&socketmode.Event{Type: socketmode.EventTypeInteractive, Data: slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: "rocket_launch_approved"}}},
+	}}) {
+		f(nil, nil)
+	}
+
+	// The default handler runs when no route matches
+	for _, f := range router.handlers(&socketmode.Event{Type: socketmode.EventTypeHello}) {
+		f(nil, nil)
+	}
+
+	want := []string{"interactive", "block_actions", "rocket_launch_approved", "default"}
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Error(diff)
+	}
+}
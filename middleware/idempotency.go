@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"log"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// IdempotencyKey identifies an event across its deliveries
+// the event_id of the Events API, the trigger_id of commands and interactions, or the envelope
+func IdempotencyKey(evt *socketmode.Event) string {
+	switch data := evt.Data.(type) {
+	case slackevents.EventsAPIEvent:
+		if callback, ok := data.Data.(*slackevents.EventsAPICallbackEvent); ok && callback.EventID != "" {
+			return "event:" + callback.EventID
+		}
+	case slack.SlashCommand:
+		if data.TriggerID != "" {
+			return "trigger:" + data.TriggerID
+		}
+	case slack.InteractionCallback:
+		if data.TriggerID != "" {
+			return "trigger:" + data.TriggerID
+		}
+	}
+
+	if evt.Request != nil && evt.Request.EnvelopeID != "" {
+		return "envelope:" + evt.Request.EnvelopeID
+	}
+
+	return ""
+}
+
+// Idempotent skips the events already handled by another delivery
+// The handlers of a same delivery share its envelope so they all run
+// A handler that panics releases the event so slack's next retry handles it
+// it should run inside Recover
+func Idempotent(store services.IdempotencyStore) Middleware {
+	return func(next Handler) Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			key := IdempotencyKey(evt)
+			if key == "" || evt.Request == nil {
+				next(evt, clt)
+				return
+			}
+
+			first, err := store.Claim(key, evt.Request.EnvelopeID)
+			if err != nil {
+				// Handling an event twice is better than losing it
+				log.Printf("ERROR unable to check %s was already handled: %v", key, err)
+				first = true
+			}
+
+			if !first {
+				log.Printf("WARNING %s was already handled, ignoring the delivery %s (retry %d: %s)",
+					key, evt.Request.EnvelopeID, evt.Request.RetryAttempt, evt.Request.RetryReason)
+
+				if !Acked(evt) {
+					Ack(evt, clt)
+				}
+				return
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					if err := store.Release(key); err != nil {
+						log.Printf("ERROR unable to release %s: %v", key, err)
+					}
+					panic(r)
+				}
+			}()
+
+			next(evt, clt)
+		}
+	}
+}
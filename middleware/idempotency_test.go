@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name string
+		evt  *socketmode.Event
+		want string
+	}{
+		{
+			"events api",
+			&socketmode.Event{
+				Data:    slackevents.EventsAPIEvent{Data: &slackevents.EventsAPICallbackEvent{EventID: "Ev1"}},
+				Request: &socketmode.Request{EnvelopeID: "envelope"},
+			},
+			"event:Ev1",
+		},
+		{
+			"slash command",
+			&socketmode.Event{Data: slack.SlashCommand{TriggerID: "T1"}, Request: &socketmode.Request{EnvelopeID: "envelope"}},
+			"trigger:T1",
+		},
+		{
+			"interaction",
+			&socketmode.Event{Data: slack.InteractionCallback{TriggerID: "T2"}, Request: &socketmode.Request{EnvelopeID: "envelope"}},
+			"trigger:T2",
+		},
+		{
+			"envelope",
+			&socketmode.Event{Data: slack.InteractionCallback{}, Request: &socketmode.Request{EnvelopeID: "envelope"}},
+			"envelope:envelope",
+		},
+		{
+			"nothing",
+			&socketmode.Event{Type: socketmode.EventTypeHello},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		if got := IdempotencyKey(test.evt); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	store := services.NewMemoryIdempotencyStore(services.SystemClock{}, time.Hour)
+	acks := NewAckManager(services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)), DefaultAutoAckAfter)
+	soccketClient := socketmode.New(slack.New("ABCD"))
+
+	delivery := func(envelope string, retry int) *socketmode.Event {
+		return &socketmode.Event{
+			Type: socketmode.EventTypeEventsAPI,
+			Data: slackevents.EventsAPIEvent{Data: &slackevents.EventsAPICallbackEvent{EventID: "Ev1"}},
+			Request: &socketmode.Request{
+				EnvelopeID:   envelope,
+				RetryAttempt: retry,
+				RetryReason:  "timeout",
+			},
+		}
+	}
+
+	greetings := 0
+	greet := Chain(func(evt *socketmode.Event, clt *socketmode.Client) {
+		greetings++
+	}, acks.Track, Idempotent(store))
+	note := 0
+	createNote := Chain(func(evt *socketmode.Event, clt *socketmode.Client) {
+		note++
+	}, acks.Track, Idempotent(store))
+
+	// Both handlers of the first delivery run
+	greet(delivery("idempotent-1", 0), soccketClient)
+	createNote(delivery("idempotent-1", 0), soccketClient)
+
+	// The retry is acknowledged and ignored
+	retry := delivery("idempotent-2", 1)
+	greet(retry, soccketClient)
+	createNote(retry, soccketClient)
+
+	if greetings != 1 || note != 1 {
+		t.Errorf("handled %d greetings and %d notes, want 1 each", greetings, note)
+	}
+
+	if !Acked(retry) {
+		t.Error("the ignored delivery was not acknowledged")
+	}
+}
+
+func TestIdempotent_Panic(t *testing.T) {
+	store := services.NewMemoryIdempotencyStore(services.SystemClock{}, time.Hour)
+	evt := func(envelope string) *socketmode.Event {
+		return &socketmode.Event{
+			Data:    slack.SlashCommand{TriggerID: "T1"},
+			Request: &socketmode.Request{EnvelopeID: envelope},
+		}
+	}
+
+	Recover(Idempotent(store)(func(evt *socketmode.Event, clt *socketmode.Client) {
+		panic("boom")
+	}))(evt("envelope-1"), nil)
+
+	// The next delivery is handled
+	handled := false
+	Idempotent(store)(func(evt *socketmode.Event, clt *socketmode.Client) {
+		handled = true
+	})(evt("envelope-2"), nil)
+
+	if !handled {
+		t.Error("the event is ignored after its handler panicked")
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Handler is how the socketmode events reach the controllers
+type Handler = socketmode.SocketmodeHandlerFunc
+
+// Middleware wraps a Handler to do some work before and/or after it
+type Middleware func(next Handler) Handler
+
+// Chain wraps a handler with middlewares, the first one runs first
+// e.g. Chain(h, Recover, Logging) is Recover(Logging(h))
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+// Describe summarizes an event for the logs
+// e.g. `slash_commands /rocket`, `interactive block_actions rocket_launch_approved`
+func Describe(evt *socketmode.Event) string {
+	parts := []string{string(evt.Type)}
+
+	switch data := evt.Data.(type) {
+	case slack.SlashCommand:
+		parts = append(parts, data.Command)
+	case slack.InteractionCallback:
+		parts = append(parts, string(data.Type))
+		for _, action := range data.ActionCallback.BlockActions {
+			parts = append(parts, action.ActionID)
+		}
+		if data.View.CallbackID != "" {
+			parts = append(parts, data.View.CallbackID)
+		}
+	case slackevents.EventsAPIEvent:
+		parts = append(parts, data.InnerEvent.Type)
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"testing"
+	"xnok/slack-go-demo/dispatch"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// record appends its name before and after the next handler
+func record(calls *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(evt *socketmode.Event, clt *socketmode.Client) {
+			*calls = append(*calls, name+" before")
+			next(evt, clt)
+			*calls = append(*calls, name+" after")
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	calls := []string{}
+	h := Chain(func(evt *socketmode.Event, clt *socketmode.Client) {
+		calls = append(calls, "handler")
+	}, record(&calls, "a"), record(&calls, "b"))
+
+	h(&socketmode.Event{}, nil)
+
+	want := []string{"a before", "b before", "handler", "b after", "a after"}
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	calls := []string{}
+	eventhandler := socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD")))
+
+	router := NewRouter(eventhandler, record(&calls, "global"))
+	router.HandleEventsAPI(slackevents.AppHomeOpened, func(evt *socketmode.Event, clt *socketmode.Client) {
+		calls = append(calls, "handler")
+	}, record(&calls, "route"))
+
+	// The handler registered on the SocketmodeHandler is wrapped
+	eventhandler.EventApiMap[slackevents.AppHomeOpened][0](&socketmode.Event{}, nil)
+
+	want := []string{"global before", "route before", "handler", "route after", "global after"}
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Error(diff)
+	}
+
+	if router.Client() != eventhandler.Client {
+		t.Error("Client() is not the client of the SocketmodeHandler")
+	}
+}
+
+func TestAutoAck(t *testing.T) {
+	called := false
+	h := AutoAck(func(evt *socketmode.Event, clt *socketmode.Client) {
+		called = true
+	})
+
+	// Events without request, e.g. hello, have nothing to acknowledge
+	h(&socketmode.Event{Type: socketmode.EventTypeHello}, nil)
+
+	if !called {
+		t.Error("the handler was not called")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		evt  socketmode.Event
+		want string
+	}{
+		{
+			name: "Slash command",
+			evt:  socketmode.Event{Type: socketmode.EventTypeSlashCommand, Data: slack.SlashCommand{Command: "/rocket"}},
+			want: "slash_commands /rocket",
+		},
+		{
+			name: "Block action",
+			evt: socketmode.Event{Type: socketmode.EventTypeInteractive, Data: slack.InteractionCallback{
+				Type:           slack.InteractionTypeBlockActions,
+				ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{ActionID: "rocket_launch_approved"}}},
+			}},
+			want: "interactive block_actions rocket_launch_approved",
+		},
+		{
+			name: "Events API",
+			evt: socketmode.Event{Type: socketmode.EventTypeEventsAPI, Data: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{Type: "app_home_opened"},
+			}},
+			want: "events_api app_home_opened",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Describe(&tt.evt); got != tt.want {
+				t.Errorf("Describe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	d := dispatch.NewDispatcher(dispatch.DefaultConfig())
+	d.Start()
+
+	handled := make(chan string, 1)
+	h := Dispatch(d)(func(evt *socketmode.Event, clt *socketmode.Client) {
+		handled <- EventType(evt)
+	})
+
+	h(&socketmode.Event{
+		Type: socketmode.EventTypeSlashCommand,
+		Data: slack.SlashCommand{Command: "/rocket"},
+	}, nil)
+	d.Stop()
+
+	if got := <-handled; got != "slash_commands /rocket" {
+		t.Errorf("handled %q", got)
+	}
+
+	if processed := d.Metrics()["slash_commands /rocket"].Processed; processed != 1 {
+		t.Errorf("processed %d events, want 1", processed)
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack/socketmode"
+)
+
+// recordRetention is how long an envelope is remembered so its handlers record it once
+const recordRetention = time.Minute
+
+// RecordedEvent is a line of a recording
+type RecordedEvent struct {
+	Time time.Time            `json:"time"`
+	Type socketmode.EventType `json:"type"`
+	// Envelope is the message received on the socket: the request and its raw payload
+	Envelope json.RawMessage `json:"envelope"`
+}
+
+// Recorder writes the incoming events as JSON Lines so they can be replayed
+// e.g. to reproduce a production bug locally
+type Recorder struct {
+	w     io.Writer
+	clock services.Clock
+	rules RedactionRules
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func NewRecorder(w io.Writer, clock services.Clock, rules RedactionRules) *Recorder {
+	return &Recorder{
+		w:     w,
+		clock: clock,
+		rules: rules,
+		seen:  make(map[string]time.Time),
+	}
+}
+
+// Record is a middleware recording every event once, even when several handlers receive it
+// it should run first so the events are recorded whatever the other middlewares do
+func (r *Recorder) Record(next Handler) Handler {
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		if err := r.record(evt); err != nil {
+			log.Printf("ERROR unable to record %s: %v", Describe(evt), err)
+		}
+
+		next(evt, clt)
+	}
+}
+
+func (r *Recorder) record(evt *socketmode.Event) error {
+	envelope, err := envelopeOf(evt)
+	if err != nil || envelope == nil {
+		return err
+	}
+
+	var id struct {
+		EnvelopeID string `json:"envelope_id"`
+	}
+	json.Unmarshal(envelope, &id)
+
+	envelope, err = r.rules.Redact(envelope)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	for seen, at := range r.seen {
+		if now.Sub(at) > recordRetention {
+			delete(r.seen, seen)
+		}
+	}
+
+	if id.EnvelopeID != "" {
+		// e.g. the app_home_opened recovered from an ErrorBadMessage shares its envelope
+		if _, ok := r.seen[id.EnvelopeID]; ok {
+			return nil
+		}
+		r.seen[id.EnvelopeID] = now
+	}
+
+	line, err := json.Marshal(RecordedEvent{Time: now, Type: evt.Type, Envelope: envelope})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
+
+// envelopeOf rebuilds the message received on the socket
+// the events of the client itself, e.g. connecting, have none
+func envelopeOf(evt *socketmode.Event) (json.RawMessage, error) {
+	if bad, ok := evt.Data.(*socketmode.ErrorBadMessage); ok {
+		return bad.Message, nil
+	}
+
+	if evt.Request == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(evt.Request)
+}
+
+// LoadRecording reads the events of a recording
+func LoadRecording(r io.Reader) ([]RecordedEvent, error) {
+	events := []RecordedEvent{}
+
+	scanner := bufio.NewScanner(r)
+	// the payloads of the views can be large
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var evt RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			return events, err
+		}
+		events = append(events, evt)
+	}
+
+	return events, scanner.Err()
+}
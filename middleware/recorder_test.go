@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+	"xnok/slack-go-demo/services"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestRedact(t *testing.T) {
+	rules := RedactionRules{
+		Fields:   []string{"profile", "token"},
+		Patterns: []string{`\+33 ?[0-9 ]{9,}`},
+	}
+	if err := rules.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := json.RawMessage(`{"token":"xoxb-1","event_time":1620475200,"user":{"id":"U1","profile":{"email":"jane@example.com","is_bot":false}},"text":"call me at +33 6 12 34 56 78"}`)
+
+	got, err := rules.Redact(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"event_time":1620475200,"text":"call me at REDACTED","token":"REDACTED","user":{"id":"U1","profile":{"email":"REDACTED","is_bot":false}}}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestDefaultRedactionRules(t *testing.T) {
+	raw := json.RawMessage(`{"user":{"id":"U1","name":"jane","real_name":"Jane Doe","profile":{"email":"jane@example.com"}},"user_name":"jane",` +
+		`"text":"10 --rocket Starship","response_url":"https://hooks.slack.com/actions/T1/1/abc","note":"ask jane@example.com",` +
+		`"view":{"id":"V1","private_metadata":"{\"response_url\":\"https:\\/\\/hooks.slack.com\\/commands\\/T1\\/2\\/def\"}",` +
+		`"state":{"values":{"note":{"description":{"type":"plain_text_input","value":"too windy"}}}}}}`)
+
+	got, err := DefaultRedactionRules().Redact(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The payload replays the same way, the fake slack still recognizes the response URLs
+	want := `{"note":"ask REDACTED","response_url":"https://hooks.slack.com/REDACTED","text":"10 --rocket Starship",` +
+		`"user":{"id":"U1","name":"REDACTED","profile":{"email":"REDACTED"},"real_name":"REDACTED"},"user_name":"REDACTED",` +
+		`"view":{"id":"V1","private_metadata":"{\"response_url\":\"https:\\/\\/hooks.slack.com\\/REDACTED\"}",` +
+		`"state":{"values":{"note":{"description":{"type":"plain_text_input","value":"too windy"}}}}}}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	clock := services.NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	out := &bytes.Buffer{}
+	recorder := NewRecorder(out, clock, DefaultRedactionRules())
+	soccketClient := socketmode.New(slack.New("ABCD"))
+
+	calls := 0
+	handler := Chain(func(evt *socketmode.Event, clt *socketmode.Client) { calls++ }, recorder.Record)
+
+	bad := json.RawMessage(`{"envelope_id":"e1","type":"events_api","payload":{"event":{"type":"app_home_opened","view":{"state":{"values":[]}}}}}`)
+	events := []*socketmode.Event{
+		// the client itself has no envelope
+		{Type: socketmode.EventTypeConnecting},
+		{Type: socketmode.EventTypeErrorBadMessage, Data: &socketmode.ErrorBadMessage{Message: bad}},
+		// recovered from the bad message
+		{Type: socketmode.EventTypeEventsAPI, Request: &socketmode.Request{Type: "events_api", EnvelopeID: "e1"}},
+		{Type: socketmode.EventTypeSlashCommand, Request: &socketmode.Request{
+			Type:       "slash_commands",
+			EnvelopeID: "e2",
+			Payload:    json.RawMessage(`{"command":"/rocket","user_name":"jane@example.com"}`),
+		}},
+	}
+	for _, evt := range events {
+		handler(evt, soccketClient)
+	}
+
+	if calls != len(events) {
+		t.Errorf("every event should reach the handler, got %d calls", calls)
+	}
+
+	recording, err := LoadRecording(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recording) != 2 {
+		t.Fatalf("every envelope should be recorded once, got %d events", len(recording))
+	}
+
+	if diff := deep.Equal(recording[0].Type, socketmode.EventTypeErrorBadMessage); diff != nil {
+		t.Error(diff)
+	}
+
+	// the bad message is kept as it was received
+	want := `{"envelope_id":"e1","payload":{"event":{"type":"app_home_opened","view":{"state":{"values":[]}}}},"type":"events_api"}`
+	if string(recording[0].Envelope) != want {
+		t.Errorf("got %s, want %s", recording[0].Envelope, want)
+	}
+
+	var request socketmode.Request
+	if err := json.Unmarshal(recording[1].Envelope, &request); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(string(request.Payload), `{"command":"/rocket","user_name":"REDACTED"}`); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"runtime/debug"
+	"sync"
+	"xnok/slack-go-demo/views"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// NewCorrelationID is a short random ID to find an error in the logs
+func NewCorrelationID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// correlationIDs are the IDs given by Recover to the events being handled
+// the request context of the handler reuses them so the panic and the logs of the handler share their ref
+var correlationIDs sync.Map
+
+// EventCorrelationID is the correlation ID given to an event being handled
+// a new one is created when the event has none
+func EventCorrelationID(evt *socketmode.Event) string {
+	if id, ok := correlationIDs.Load(evt); ok {
+		return id.(string)
+	}
+
+	return NewCorrelationID()
+}
+
+// Recover keeps a panicking handler from taking the whole App down
+// the panic is logged with the envelope of the event, the request is acknowledged
+// so slack does not retry it and the user who triggered it is told something went wrong
+func Recover(next Handler) Handler {
+	return func(evt *socketmode.Event, clt *socketmode.Client) {
+		ref := EventCorrelationID(evt)
+		correlationIDs.Store(evt, ref)
+
+		defer func() {
+			defer correlationIDs.Delete(evt)
+
+			p := recover()
+			if p == nil {
+				return
+			}
+
+			envelope, _ := json.Marshal(evt.Request)
+			log.Printf("ERROR panic while handling %s (ref: %s): %v\nenvelope: %s\n%s", Describe(evt), ref, p, envelope, debug.Stack())
+
+			if clt == nil {
+				return
+			}
+
+			// The handler may have acknowledged the request already
+			if !Acked(evt) {
+				Ack(evt, clt)
+			}
+
+			reportFailure(evt, clt, ref)
+		}()
+
+		next(evt, clt)
+	}
+}
+
+// failureTarget is where the user who triggered an event can be told about a failure
+type failureTarget struct {
+	User        string
+	Channel     string
+	ResponseURL string
+}
+
+// failureTargetOf finds who triggered an event and where
+// events without a channel, e.g. in the App Home, cannot be answered
+func failureTargetOf(evt *socketmode.Event) (failureTarget, bool) {
+	target := failureTarget{}
+
+	switch data := evt.Data.(type) {
+	case slack.SlashCommand:
+		target = failureTarget{User: data.UserID, Channel: data.ChannelID, ResponseURL: data.ResponseURL}
+	case slack.InteractionCallback:
+		target = failureTarget{User: data.User.ID, Channel: data.Channel.ID, ResponseURL: data.ResponseURL}
+		if target.Channel == "" {
+			target.Channel = data.Container.ChannelID
+		}
+	case slackevents.EventsAPIEvent:
+		switch inner := data.InnerEvent.Data.(type) {
+		case *slackevents.AppMentionEvent:
+			target = failureTarget{User: inner.User, Channel: inner.Channel}
+		case *slackevents.MemberJoinedChannelEvent:
+			target = failureTarget{User: inner.User, Channel: inner.Channel}
+		}
+	}
+
+	return target, target.ResponseURL != "" || (target.User != "" && target.Channel != "")
+}
+
+// reportFailure shows the user an ephemeral message with the correlation ID
+func reportFailure(evt *socketmode.Event, clt *socketmode.Client, ref string) {
+	target, ok := failureTargetOf(evt)
+	if !ok {
+		return
+	}
+
+	blocks := views.SomethingWentWrong(ref)
+	api := clt.GetApiClient()
+
+	var err error
+	if target.ResponseURL != "" {
+		_, _, err = api.PostMessage(
+			target.Channel,
+			slack.MsgOptionBlocks(blocks...),
+			slack.MsgOptionResponseURL(target.ResponseURL, slack.ResponseTypeEphemeral),
+		)
+	} else {
+		_, err = api.PostEphemeral(target.Channel, target.User, slack.MsgOptionBlocks(blocks...))
+	}
+
+	if err != nil {
+		log.Printf("ERROR unable to report the failure %s to %s: %v", ref, target.User, err)
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestRecover(t *testing.T) {
+
+	// The user is told through the response URL or with an ephemeral message
+	answers := map[string]string{}
+
+	mux := http.NewServeMux()
+	for _, path := range []string{"/response", "/chat.postEphemeral"} {
+		path := path
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			// chat.postEphemeral is form encoded
+			body, _ := ioutil.ReadAll(r.Body)
+			decoded, _ := url.QueryUnescape(string(body))
+			answers[path] = decoded
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		})
+	}
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	soccketClient := socketmode.New(
+		slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/")),
+	)
+
+	h := Recover(func(evt *socketmode.Event, clt *socketmode.Client) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name string
+		evt  *socketmode.Event
+		path string
+	}{
+		{
+			name: "Slash command",
+			evt: &socketmode.Event{
+				Type:    socketmode.EventTypeSlashCommand,
+				Data:    slack.SlashCommand{Command: "/rocket", UserID: "U0123456", ChannelID: "C0123456", ResponseURL: testServer.URL + "/response"},
+				Request: &socketmode.Request{EnvelopeID: "dummy"},
+			},
+			path: "/response",
+		},
+		{
+			name: "App mention",
+			evt: &socketmode.Event{
+				Type: socketmode.EventTypeEventsAPI,
+				Data: slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{
+					Type: "app_mention",
+					Data: &slackevents.AppMentionEvent{User: "U0123456", Channel: "C0123456"},
+				}},
+				Request: &socketmode.Request{EnvelopeID: "dummy"},
+			},
+			path: "/chat.postEphemeral",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The panic does not reach the caller
+			h(tt.evt, soccketClient)
+
+			if answer := answers[tt.path]; !strings.Contains(answer, "Something went wrong (ref:") {
+				t.Errorf("answer = %q", answer)
+			}
+		})
+	}
+
+	// Without a client there is nobody to tell
+	h(&socketmode.Event{Type: socketmode.EventTypeSlashCommand}, nil)
+}
+
+func TestRecover_CorrelationID(t *testing.T) {
+
+	// The user is told the ref of the failure
+	var answer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		answer = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	soccketClient := socketmode.New(
+		slack.New("ABCD", slack.OptionAPIURL(testServer.URL+"/")),
+	)
+
+	router := NewRouter(socketmode.NewsSocketmodeHandler(soccketClient))
+
+	// The handler logs with the ref of its request context before panicking
+	var ref string
+	h := Recover(router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		ref = CorrelationID(ctx)
+		panic("boom")
+	}, 0))
+
+	evt := &socketmode.Event{
+		Type:    socketmode.EventTypeSlashCommand,
+		Data:    slack.SlashCommand{Command: "/rocket", UserID: "U0123456", ChannelID: "C0123456", ResponseURL: testServer.URL + "/response"},
+		Request: &socketmode.Request{EnvelopeID: "dummy"},
+	}
+	h(evt, soccketClient)
+
+	if ref == "" || !strings.Contains(answer, ref) {
+		t.Errorf("answer = %q, want the ref %q of the request context", answer, ref)
+	}
+
+	// The event is forgotten once handled
+	if got := EventCorrelationID(evt); got == ref {
+		t.Errorf("EventCorrelationID() = %v, want a new ID", got)
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	a, b := NewCorrelationID(), NewCorrelationID()
+	if len(a) != 8 || a == b {
+		t.Errorf("NewCorrelationID() = %v, %v", a, b)
+	}
+}
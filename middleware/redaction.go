@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+)
+
+// Redacted replaces the personal information in the recordings
+const Redacted = "REDACTED"
+
+// RedactionRules hide the personal information of the recorded events
+// only the strings are replaced so a redacted recording still parses the same way
+type RedactionRules struct {
+	// Fields are redacted wherever they appear, e.g. `email` or `profile`
+	Fields []string `json:"fields"`
+	// Patterns are regular expressions redacted from every string, e.g. phone numbers
+	Patterns []string `json:"patterns"`
+
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// DefaultRedactionRules hide the names, the emails and phone numbers of the users and the tokens
+// What the users wrote and the views state are kept so the recordings replay the same way,
+// only the emails they contain are hidden
+// The response URLs keep their host for the replay, their secret path can post in the channel until they expire
+func DefaultRedactionRules() RedactionRules {
+	rules := RedactionRules{
+		Fields: []string{
+			"email", "phone", "token",
+			"name", "user_name", "real_name", "real_name_normalized", "display_name", "display_name_normalized",
+			"first_name", "last_name", "title",
+		},
+		Patterns: []string{
+			`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+			// e.g. https://hooks.slack.com/actions/T0123456/1234/abc, escaped as well inside the private metadata
+			`(?:actions|commands)\\?/T[A-Z0-9]+\\?/[0-9]+\\?/[A-Za-z0-9]+`,
+		},
+	}
+
+	// the default rules are valid
+	rules.compile()
+
+	return rules
+}
+
+// LoadRedactionRules reads the redaction rules file
+// When no file is provided we use the DefaultRedactionRules
+func LoadRedactionRules(file string) (RedactionRules, error) {
+	if file == "" {
+		return DefaultRedactionRules(), nil
+	}
+
+	rules := RedactionRules{}
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return rules, err
+	}
+
+	if err := json.Unmarshal(str, &rules); err != nil {
+		return rules, err
+	}
+
+	return rules, rules.compile()
+}
+
+func (r *RedactionRules) compile() error {
+	r.fields = make(map[string]bool)
+	for _, field := range r.Fields {
+		r.fields[field] = true
+	}
+
+	r.patterns = nil
+	for _, pattern := range r.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return nil
+}
+
+// Redact applies the rules to a JSON document
+func (r RedactionRules) Redact(raw json.RawMessage) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	// keep the numbers as they are, e.g. timestamps
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(r.redact(doc, false))
+}
+
+// redact walks the document, every string below a redacted field is replaced
+func (r RedactionRules) redact(value interface{}, redacted bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = r.redact(child, redacted || r.fields[key])
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.redact(child, redacted)
+		}
+		return v
+	case string:
+		if redacted {
+			return Redacted
+		}
+		for _, re := range r.patterns {
+			v = re.ReplaceAllString(v, Redacted)
+		}
+		return v
+	}
+
+	return value
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// transientErrors are the errors of the Slack API worth retrying
+var transientErrors = map[string]bool{
+	"internal_error":      true,
+	"fatal_error":         true,
+	"ratelimited":         true,
+	"request_timeout":     true,
+	"service_unavailable": true,
+}
+
+// RetryPolicy retries the ContextHandlers failing with a transient error
+// the delay between two attempts doubles from InitialDelayMs up to MaxDelayMs
+type RetryPolicy struct {
+	// Attempts is how many times a handler runs at most, 1 does not retry
+	Attempts       int     `json:"attempts"`
+	InitialDelayMs int     `json:"initial_delay_ms"`
+	MaxDelayMs     int     `json:"max_delay_ms"`
+	Multiplier     float64 `json:"multiplier"`
+}
+
+// DefaultRetryPolicy gives the Slack API about 3 seconds to recover
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:       3,
+		InitialDelayMs: 1000,
+		MaxDelayMs:     10000,
+		Multiplier:     2,
+	}
+}
+
+// LoadRetryPolicy reads the retry policy file
+// When no file is provided we use the DefaultRetryPolicy
+func LoadRetryPolicy(file string) (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+	if file == "" {
+		return policy, nil
+	}
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return policy, err
+	}
+
+	if err := json.Unmarshal(str, &policy); err != nil {
+		return policy, err
+	}
+
+	return policy, policy.Validate()
+}
+
+// Validate checks the policy can be applied
+func (p RetryPolicy) Validate() error {
+	if p.Attempts < 1 {
+		return fmt.Errorf("a handler must run at least once, got %d attempts", p.Attempts)
+	}
+
+	if p.InitialDelayMs < 0 || p.MaxDelayMs < p.InitialDelayMs {
+		return fmt.Errorf("the delays must be positive and the max delay above %dms, got %dms", p.InitialDelayMs, p.MaxDelayMs)
+	}
+
+	if p.Multiplier < 1 {
+		return fmt.Errorf("the delays cannot decrease, got a multiplier of %v", p.Multiplier)
+	}
+
+	return nil
+}
+
+// Backoff is the delay before the attempt following attempt, e.g. 1s, 2s, 4s
+// slack tells how long to wait when the App is rate limited
+func (p RetryPolicy) Backoff(attempt int, err error) time.Duration {
+	delay := float64(p.InitialDelayMs)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+	}
+
+	backoff := time.Duration(delay) * time.Millisecond
+	if max := time.Duration(p.MaxDelayMs) * time.Millisecond; backoff > max {
+		backoff = max
+	}
+
+	var limited *slack.RateLimitedError
+	if errors.As(err, &limited) && limited.RetryAfter > backoff {
+		backoff = limited.RetryAfter
+	}
+
+	return backoff
+}
+
+// Retry tells if the attempt that failed with err can be followed by another one
+func (p RetryPolicy) Retry(attempt int, err error) bool {
+	return attempt < p.Attempts && Transient(err)
+}
+
+// Transient tells if an error of the Slack API may not happen again
+// e.g. the App is rate limited, slack is unavailable or the network failed
+func Transient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// the handler timed out or the App shuts down
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// e.g. slack.RateLimitedError and the 5xx status codes
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// e.g. connection refused or reset
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	// the error codes of the Slack API are plain errors, usually wrapped by the handlers
+	for ; err != nil; err = errors.Unwrap(err) {
+		if transientErrors[err.Error()] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &slack.RateLimitedError{RetryAfter: time.Second}, true},
+		{"slack error", errors.New("internal_error"), true},
+		{"wrapped", fmt.Errorf("publishing the home tab: %w", &slack.RateLimitedError{}), true},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"timeout", context.DeadlineExceeded, false},
+		{"shutdown", context.Canceled, false},
+		{"wrapped slack error", fmt.Errorf("startOnboarding day 1: %w", errors.New("internal_error")), true},
+		{"invalid request", errors.New("channel_not_found"), false},
+		{"wrapped invalid request", fmt.Errorf("reactToMention: %w", errors.New("channel_not_found")), false},
+		{"nil", nil, false},
+	}
+
+	for _, test := range tests {
+		if got := Transient(test.err); got != test.want {
+			t.Errorf("%s: Transient(%v) = %v, want %v", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, InitialDelayMs: 100, MaxDelayMs: 300, Multiplier: 2}
+
+	tests := []struct {
+		attempt int
+		err     error
+		want    time.Duration
+	}{
+		{1, errors.New("internal_error"), 100 * time.Millisecond},
+		{2, errors.New("internal_error"), 200 * time.Millisecond},
+		{3, errors.New("internal_error"), 300 * time.Millisecond},
+		// slack asks to wait longer
+		{1, &slack.RateLimitedError{RetryAfter: 2 * time.Second}, 2 * time.Second},
+	}
+
+	for _, test := range tests {
+		if got := policy.Backoff(test.attempt, test.err); got != test.want {
+			t.Errorf("Backoff(%d, %v) = %v, want %v", test.attempt, test.err, got, test.want)
+		}
+	}
+
+	if policy.Retry(5, errors.New("internal_error")) {
+		t.Error("the last attempt is retried")
+	}
+}
+
+func TestLoadRetryPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "retry.json")
+	ioutil.WriteFile(file, []byte(`{"attempts":0}`), 0644)
+
+	if _, err := LoadRetryPolicy(file); err == nil {
+		t.Error("a policy without attempts is valid")
+	}
+
+	policy, err := LoadRetryPolicy("")
+	if err != nil || policy != DefaultRetryPolicy() {
+		t.Errorf("LoadRetryPolicy(\"\") = %+v, %v", policy, err)
+	}
+}
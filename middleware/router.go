@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"xnok/slack-go-demo/dispatch"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Router registers the handlers on the SocketmodeHandler through middlewares
+// The global middlewares run first, then the middlewares given with the route
+type Router struct {
+	EventHandler *socketmode.SocketmodeHandler
+	// BaseContext is the parent of the contexts given to the ContextHandlers, cancelled on shutdown
+	BaseContext context.Context
+	// Users resolves the user of the ContextHandlers, only the user ID is known without it
+	Users UserInfoGetter
+	// Retry runs the ContextHandlers failing with a transient error again, they run once by default
+	Retry RetryPolicy
+	// DeadLetters keeps the events whose ContextHandler failed, they are only logged without it
+	DeadLetters services.DeadLetterStore
+	// Clock dates the dead letters and waits between the attempts, the SystemClock by default
+	Clock services.Clock
+
+	middlewares []Middleware
+	dispatcher  *dispatch.Dispatcher
+
+	mu     sync.Mutex
+	routes map[string]contextRoute
+}
+
+func NewRouter(eventhandler *socketmode.SocketmodeHandler, middlewares ...Middleware) *Router {
+	return &Router{
+		EventHandler: eventhandler,
+		middlewares:  middlewares,
+	}
+}
+
+// Use adds global middlewares
+// they only wrap the routes registered afterwards
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Client is the socketmode client events are received from
+func (r *Router) Client() *socketmode.Client {
+	return r.EventHandler.Client
+}
+
+func (r *Router) wrap(f Handler, middlewares []Middleware) Handler {
+	all := append(append([]Middleware{}, r.middlewares...), middlewares...)
+	return Chain(f, all...)
+}
+
+// Handle registers a handler for a socketmode event type
+func (r *Router) Handle(et socketmode.EventType, f Handler, middlewares ...Middleware) {
+	r.EventHandler.Handle(et, r.wrap(f, middlewares))
+}
+
+// HandleInteraction registers a handler for an interaction type
+func (r *Router) HandleInteraction(et slack.InteractionType, f Handler, middlewares ...Middleware) {
+	r.EventHandler.HandleInteraction(et, r.wrap(f, middlewares))
+}
+
+// HandleInteractionBlockAction registers a handler for a block action referenced by its ActionID
+func (r *Router) HandleInteractionBlockAction(actionID string, f Handler, middlewares ...Middleware) {
+	r.EventHandler.HandleInteractionBlockAction(actionID, r.wrap(f, middlewares))
+}
+
+// HandleEventsAPI registers a handler for an Events API event type
+func (r *Router) HandleEventsAPI(et slackevents.EventAPIType, f Handler, middlewares ...Middleware) {
+	r.EventHandler.HandleEventsAPI(et, r.wrap(f, middlewares))
+}
+
+// HandleSlashCommand registers a handler for a slash command
+func (r *Router) HandleSlashCommand(command string, f Handler, middlewares ...Middleware) {
+	r.EventHandler.HandleSlashCommand(command, r.wrap(f, middlewares))
+}
+
+// HandleDefault registers the handler of the events no route matches
+func (r *Router) HandleDefault(f Handler, middlewares ...Middleware) {
+	r.EventHandler.HandleDefault(r.wrap(f, middlewares))
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// KeyFunc extracts what the events handled in order share
+// events with an empty key are not ordered
+type KeyFunc func(evt *socketmode.Event) string
+
+// UserKey orders the events of a user, e.g. the App Home opened before a note is submitted
+func UserKey(evt *socketmode.Event) string {
+	switch data := evt.Data.(type) {
+	case slack.SlashCommand:
+		return data.UserID
+	case slack.InteractionCallback:
+		return data.User.ID
+	case slackevents.EventsAPIEvent:
+		switch inner := data.InnerEvent.Data.(type) {
+		case slackevents.AppHomeOpenedEvent:
+			return inner.User
+		case *slackevents.AppHomeOpenedEvent:
+			return inner.User
+		case *slackevents.AppMentionEvent:
+			return inner.User
+		case *slackevents.MemberJoinedChannelEvent:
+			return inner.User
+		case *slack.TeamJoinEvent:
+			return inner.User.ID
+		case *slack.UserChangeEvent:
+			return inner.User.ID
+		}
+	}
+
+	return ""
+}
+
+// ChannelKey orders the events of a channel
+func ChannelKey(evt *socketmode.Event) string {
+	switch data := evt.Data.(type) {
+	case slack.SlashCommand:
+		return data.ChannelID
+	case slack.InteractionCallback:
+		if data.Channel.ID != "" {
+			return data.Channel.ID
+		}
+		return data.Container.ChannelID
+	case slackevents.EventsAPIEvent:
+		switch inner := data.InnerEvent.Data.(type) {
+		case slackevents.AppHomeOpenedEvent:
+			return inner.Channel
+		case *slackevents.AppHomeOpenedEvent:
+			return inner.Channel
+		case *slackevents.AppMentionEvent:
+			return inner.Channel
+		case *slackevents.MemberJoinedChannelEvent:
+			return inner.Channel
+		case *slack.ChannelRenameEvent:
+			return inner.Channel.ID
+		}
+	}
+
+	return ""
+}
+
+// ViewKey orders the events of a view, e.g. the actions and the submission of a modal
+func ViewKey(evt *socketmode.Event) string {
+	switch data := evt.Data.(type) {
+	case slack.InteractionCallback:
+		if data.View.ID != "" {
+			return data.View.ID
+		}
+		return data.Container.ViewID
+	case slackevents.EventsAPIEvent:
+		switch inner := data.InnerEvent.Data.(type) {
+		case slackevents.AppHomeOpenedEvent:
+			return inner.View.ID
+		case *slackevents.AppHomeOpenedEvent:
+			return inner.View.ID
+		}
+	}
+
+	return ""
+}
+
+// ShardKey finds the KeyFunc named in the dispatcher configuration
+// an empty name does not order the events
+func ShardKey(name string) (KeyFunc, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "user":
+		return UserKey, nil
+	case "channel":
+		return ChannelKey, nil
+	case "view":
+		return ViewKey, nil
+	}
+
+	return nil, fmt.Errorf("unknown shard key %q, expected user, channel or view", name)
+}
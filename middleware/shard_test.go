@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestShardKeys(t *testing.T) {
+	homeOpened := &socketmode.Event{
+		Type: socketmode.EventTypeEventsAPI,
+		Data: slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: string(slackevents.AppHomeOpened),
+			Data: slackevents.AppHomeOpenedEvent{User: "U1", Channel: "D1", View: slack.View{ID: "V1"}},
+		}},
+	}
+	noteSubmitted := &socketmode.Event{
+		Type: socketmode.EventTypeInteractive,
+		Data: slack.InteractionCallback{
+			Type: slack.InteractionTypeViewSubmission,
+			User: slack.User{ID: "U1"},
+			View: slack.View{ID: "V2"},
+		},
+	}
+	command := &socketmode.Event{
+		Type: socketmode.EventTypeSlashCommand,
+		Data: slack.SlashCommand{UserID: "U2", ChannelID: "C1"},
+	}
+
+	tests := []struct {
+		name string
+		key  KeyFunc
+		evt  *socketmode.Event
+		want string
+	}{
+		{"user of app_home_opened", UserKey, homeOpened, "U1"},
+		{"user of a view submission", UserKey, noteSubmitted, "U1"},
+		{"user of a slash command", UserKey, command, "U2"},
+		{"channel of app_home_opened", ChannelKey, homeOpened, "D1"},
+		{"channel of a slash command", ChannelKey, command, "C1"},
+		{"view of app_home_opened", ViewKey, homeOpened, "V1"},
+		{"view of a view submission", ViewKey, noteSubmitted, "V2"},
+		{"slash commands have no view", ViewKey, command, ""},
+		{"unknown events are not ordered", UserKey, &socketmode.Event{Type: socketmode.EventTypeHello}, ""},
+	}
+
+	for _, test := range tests {
+		if got := test.key(test.evt); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestShardKey(t *testing.T) {
+	for _, name := range []string{"", "user", "channel", "view"} {
+		if _, err := ShardKey(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	if _, err := ShardKey("team"); err == nil {
+		t.Error("an unknown shard key is valid")
+	}
+}
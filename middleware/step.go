@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+)
+
+// steps are the results of the steps of a request that succeeded
+// they are kept for the next attempts of its handler
+type steps struct {
+	mu   sync.Mutex
+	done map[string]interface{}
+}
+
+// Step runs f once per request, the attempts following its success get its result without running it again
+// e.g. a handler retried because its second message failed does not post the first one twice
+// f always runs outside of a request context
+func Step(ctx context.Context, name string, f func() (interface{}, error)) (interface{}, error) {
+	s, ok := ctx.Value(stepsKey).(*steps)
+	if !ok {
+		return f()
+	}
+
+	s.mu.Lock()
+	result, done := s.done[name]
+	s.mu.Unlock()
+	if done {
+		return result, nil
+	}
+
+	result, err := f()
+	if err != nil {
+		return result, err
+	}
+
+	s.mu.Lock()
+	s.done[name] = result
+	s.mu.Unlock()
+
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestStep(t *testing.T) {
+	router := NewRouter(socketmode.NewsSocketmodeHandler(socketmode.New(slack.New("ABCD"))))
+	router.Retry = RetryPolicy{Attempts: 3, Multiplier: 1}
+
+	// The greeting is posted, the introduction fails once
+	posted, attempts := 0, 0
+	router.WithContext(func(ctx context.Context, evt *socketmode.Event, clt *socketmode.Client) error {
+		attempts++
+
+		ts, err := Step(ctx, "greeting", func() (interface{}, error) {
+			posted++
+			return "1.1", nil
+		})
+		if err != nil || ts != "1.1" {
+			t.Errorf("Step() = %v, %v", ts, err)
+		}
+
+		if attempts == 1 {
+			return errors.New("internal_error")
+		}
+		return nil
+	}, 0)(&socketmode.Event{Type: socketmode.EventTypeEventsAPI}, nil)
+
+	if attempts != 2 || posted != 1 {
+		t.Errorf("%d attempts posted %d greetings, want 2 attempts and 1 greeting", attempts, posted)
+	}
+
+	// A failed step runs again
+	failures := 0
+	ctx := NewRequestContext(context.Background(), &socketmode.Event{}, nil)
+	for i := 0; i < 2; i++ {
+		Step(ctx, "introduction", func() (interface{}, error) {
+			failures++
+			return nil, errors.New("internal_error")
+		})
+	}
+	if failures != 2 {
+		t.Errorf("the failed step ran %d times, want 2", failures)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"xnok/slack-go-demo/drivers"
+	"xnok/slack-go-demo/middleware"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+// replay feeds a recording to the controllers against a fake slack
+// e.g. to reproduce locally the ErrorBadMessage of app_home_opened
+// it returns the exit status once the handlers are done
+func replay(file string) int {
+	// The configuration of the recorded App, the tokens are not needed
+	godotenv.Load("./test_slack.env")
+
+	// The replay must not change the stores of the App
+	os.Unsetenv("IDEMPOTENCY_STORE_FILE")
+	os.Unsetenv("ASSET_CACHE_FILE")
+	os.Unsetenv("DEAD_LETTER_STORE_FILE")
+
+	f, err := os.Open(file)
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Unable to open the recording")
+
+		return exitFailure
+	}
+	defer f.Close()
+
+	events, err := middleware.LoadRecording(f)
+	if err != nil {
+		log.Error().
+			Str("error", err.Error()).
+			Msg("Invalid recording")
+
+		return exitFailure
+	}
+
+	envelopes := []json.RawMessage{}
+	for _, evt := range events {
+		envelopes = append(envelopes, evt.Envelope)
+	}
+
+	fake := drivers.NewFakeSlack(envelopes)
+	defer fake.Close()
+
+	app := newApp(fake.Client())
+	go app.router.RunEventLoop()
+
+	<-fake.Done()
+
+	status := app.shutdown()
+
+	log.Info().
+		Int("events", len(events)).
+		Int("api_calls", len(fake.Calls())).
+		Msg("Replay done")
+
+	return status
+}
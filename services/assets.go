@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// AssetScheme references an image embedded in the App by its name
+// e.g. "image_url": "asset://rocket0.png" in a view or a frame of the rocket catalog
+const AssetScheme = "asset://"
+
+// FileUploader is the part of the slack API used to publish the assets
+type FileUploader interface {
+	UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error)
+}
+
+// AssetRef is an embedded image once uploaded to slack
+// SHA256 is the checksum of the uploaded content so a changed image is uploaded again
+type AssetRef struct {
+	FileID string `json:"file_id"`
+	SHA256 string `json:"sha256"`
+}
+
+// AssetPublisher uploads the images embedded in the App with files.upload
+// the views then reference the slack files instead of an external URL
+// The file references are kept in cacheFile, when set, so a restart does not upload them again
+type AssetPublisher struct {
+	api       FileUploader
+	assets    fs.FS
+	cacheFile string
+
+	mu   sync.RWMutex
+	refs map[string]AssetRef
+}
+
+func NewAssetPublisher(api FileUploader, assets fs.FS, cacheFile string) *AssetPublisher {
+	return &AssetPublisher{
+		api:       api,
+		assets:    assets,
+		cacheFile: cacheFile,
+		refs:      make(map[string]AssetRef),
+	}
+}
+
+// Publish uploads the assets that are not in the cache yet
+// It stops at the first failure, the assets already uploaded are kept
+func (p *AssetPublisher) Publish(ctx context.Context) error {
+	if err := p.loadCache(); err != nil {
+		return err
+	}
+
+	names, err := fs.Glob(p.assets, "*")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, err := fs.ReadFile(p.assets, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		checksum := hex.EncodeToString(sum[:])
+
+		if ref, ok := p.Ref(name); ok && ref.SHA256 == checksum {
+			continue
+		}
+
+		file, err := p.api.UploadFileContext(ctx, slack.FileUploadParameters{
+			Reader:   bytes.NewReader(content),
+			Filename: name,
+			Title:    name,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to upload %s: %w", name, err)
+		}
+
+		p.mu.Lock()
+		p.refs[name] = AssetRef{FileID: file.ID, SHA256: checksum}
+		p.mu.Unlock()
+
+		if err := p.saveCache(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ref returns the slack file of an asset once it is published
+func (p *AssetPublisher) Ref(name string) (AssetRef, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ref, ok := p.refs[name]
+	return ref, ok
+}
+
+// FileID returns the slack file ID of an asset once it is published
+func (p *AssetPublisher) FileID(name string) (string, bool) {
+	ref, ok := p.Ref(name)
+	return ref.FileID, ok
+}
+
+// loadCache reads the file references of a previous run
+// a missing cache file simply means nothing was uploaded yet
+func (p *AssetPublisher) loadCache() error {
+	if p.cacheFile == "" {
+		return nil
+	}
+
+	str, err := ioutil.ReadFile(p.cacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	refs := map[string]AssetRef{}
+	if err := json.Unmarshal(str, &refs); err != nil {
+		return fmt.Errorf("invalid asset cache %s: %w", p.cacheFile, err)
+	}
+
+	p.mu.Lock()
+	for name, ref := range refs {
+		p.refs[name] = ref
+	}
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *AssetPublisher) saveCache() error {
+	if p.cacheFile == "" {
+		return nil
+	}
+
+	p.mu.RLock()
+	str, err := json.MarshalIndent(p.refs, "", "  ")
+	p.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p.cacheFile, str, 0644)
+}
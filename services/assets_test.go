@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+)
+
+type fakeUploader struct {
+	uploads []string
+	err     error
+}
+
+func (f *fakeUploader) UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	content, _ := ioutil.ReadAll(params.Reader)
+	f.uploads = append(f.uploads, params.Filename+":"+string(content))
+
+	return &slack.File{ID: "F" + params.Filename}, nil
+}
+
+func TestAssetPublisher_Publish(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "assets.json")
+	assets := fstest.MapFS{
+		"rocket0.png": {Data: []byte("0")},
+		"rocket1.png": {Data: []byte("1")},
+	}
+
+	api := &fakeUploader{}
+	publisher := NewAssetPublisher(api, assets, cache)
+
+	if _, ok := publisher.FileID("rocket0.png"); ok {
+		t.Error("FileID() found an asset before publishing")
+	}
+
+	if err := publisher.Publish(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok := publisher.FileID("rocket0.png"); !ok || id != "Frocket0.png" {
+		t.Errorf("FileID() = %v, %v", id, ok)
+	}
+
+	// A restart reads the cache, only the changed image is uploaded again
+	assets["rocket1.png"] = &fstest.MapFile{Data: []byte("one")}
+
+	api = &fakeUploader{}
+	if err := NewAssetPublisher(api, assets, cache).Publish(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(api.uploads, []string{"rocket1.png:one"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestAssetPublisher_PublishError(t *testing.T) {
+	publisher := NewAssetPublisher(&fakeUploader{err: errors.New("not_allowed")}, fstest.MapFS{
+		"rocket0.png": {Data: []byte("0")},
+	}, "")
+
+	if err := publisher.Publish(context.Background()); err == nil {
+		t.Error("Publish() expected an error")
+	}
+
+	if _, ok := publisher.FileID("rocket0.png"); ok {
+		t.Error("FileID() found an asset that failed to upload")
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// BuddyStrategy defines how a buddy is picked from a pool
+type BuddyStrategy string
+
+const (
+	BuddyRoundRobin   BuddyStrategy = "round_robin"
+	BuddyLeastLoaded  BuddyStrategy = "least_loaded"
+	BuddySameTimezone BuddyStrategy = "same_timezone"
+)
+
+// BuddyPool is the list of buddies available for new members of a channel
+type BuddyPool struct {
+	Channel  string        `json:"channel"`
+	Strategy BuddyStrategy `json:"strategy"`
+	Buddies  []string      `json:"buddies"`
+}
+
+// BuddyConfig is the content of the buddy pools definition file
+type BuddyConfig struct {
+	Pools []BuddyPool `json:"pools"`
+}
+
+// Pairing records that a buddy is in charge of a mentee
+type Pairing struct {
+	Buddy   string
+	Mentee  string
+	Channel string
+	Since   time.Time
+}
+
+// UserInfoGetter is the part of the slack API we need to compare timezones
+type UserInfoGetter interface {
+	GetUserInfo(user string) (*slack.User, error)
+}
+
+// LoadBuddyConfig reads the buddy pools definition file
+// When no file is provided no pool is configured
+func LoadBuddyConfig(file string) (BuddyConfig, error) {
+	config := BuddyConfig{}
+
+	if file == "" {
+		return config, nil
+	}
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(str, &config); err != nil {
+		return config, err
+	}
+
+	for _, pool := range config.Pools {
+		switch pool.Strategy {
+		case BuddyRoundRobin, BuddyLeastLoaded, BuddySameTimezone:
+		default:
+			return config, fmt.Errorf("unknown buddy strategy %q for channel %s", pool.Strategy, pool.Channel)
+		}
+	}
+
+	return config, nil
+}
+
+// BuddyService assigns buddies to new channel members and keeps track of the pairings
+type BuddyService struct {
+	mu       sync.Mutex
+	pools    map[string]BuddyPool
+	next     map[string]int
+	pairings []Pairing
+}
+
+func NewBuddyService(config BuddyConfig) *BuddyService {
+	pools := make(map[string]BuddyPool)
+	for _, pool := range config.Pools {
+		pools[pool.Channel] = pool
+	}
+
+	return &BuddyService{
+		pools: pools,
+		next:  make(map[string]int),
+	}
+}
+
+// Pick chooses a buddy for a member who joined a channel, the pairing is not recorded until Commit
+// It returns false when the channel has no pool, nobody is available or the member already has a buddy
+func (s *BuddyService) Pick(channel string, mentee *slack.User, users UserInfoGetter) (Pairing, bool) {
+	s.mu.Lock()
+	pool, ok := s.pools[channel]
+	s.mu.Unlock()
+
+	if !ok {
+		return Pairing{}, false
+	}
+
+	// The timezones are resolved before locking, they may call the slack API
+	var offsets map[string]int
+	if pool.Strategy == BuddySameTimezone {
+		offsets = timezones(pool.Buddies, users)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A member cannot be their own buddy and only get one buddy per channel
+	candidates := []string{}
+	for _, buddy := range pool.Buddies {
+		if buddy == mentee.ID {
+			continue
+		}
+		candidates = append(candidates, buddy)
+	}
+
+	if s.paired(channel, mentee.ID) {
+		return Pairing{}, false
+	}
+
+	if len(candidates) == 0 {
+		return Pairing{}, false
+	}
+
+	var buddy string
+	switch pool.Strategy {
+	case BuddyLeastLoaded:
+		buddy = s.leastLoaded(candidates)
+	case BuddySameTimezone:
+		buddy = s.sameTimezone(candidates, mentee, offsets)
+	default:
+		buddy = candidates[s.next[channel]%len(candidates)]
+	}
+
+	pairing := Pairing{
+		Buddy:   buddy,
+		Mentee:  mentee.ID,
+		Channel: channel,
+		Since:   time.Now(),
+	}
+
+	return pairing, true
+}
+
+// Commit records a pairing once the buddy was introduced
+// It returns false when the mentee got a buddy in the meantime, e.g. the member joined twice
+func (s *BuddyService) Commit(pairing Pairing) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paired(pairing.Channel, pairing.Mentee) {
+		return false
+	}
+
+	s.pairings = append(s.pairings, pairing)
+
+	// the round robin moves on only when the buddy was actually introduced
+	switch s.pools[pairing.Channel].Strategy {
+	case BuddyLeastLoaded, BuddySameTimezone:
+	default:
+		s.next[pairing.Channel]++
+	}
+
+	return true
+}
+
+// paired is true when the mentee already has a buddy in the channel
+func (s *BuddyService) paired(channel string, mentee string) bool {
+	for _, p := range s.pairings {
+		if p.Mentee == mentee && p.Channel == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Mentees returns the pairings in which the user is the buddy
+func (s *BuddyService) Mentees(buddy string) []Pairing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mentees := []Pairing{}
+	for _, p := range s.pairings {
+		if p.Buddy == buddy {
+			mentees = append(mentees, p)
+		}
+	}
+
+	return mentees
+}
+
+func (s *BuddyService) load(buddy string) int {
+	count := 0
+	for _, p := range s.pairings {
+		if p.Buddy == buddy {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *BuddyService) leastLoaded(candidates []string) string {
+	best := candidates[0]
+	for _, buddy := range candidates[1:] {
+		if s.load(buddy) < s.load(best) {
+			best = buddy
+		}
+	}
+
+	return best
+}
+
+// timezones reads the timezone offset of the buddies, the ones that cannot be found are left out
+func timezones(buddies []string, users UserInfoGetter) map[string]int {
+	offsets := make(map[string]int)
+	for _, buddy := range buddies {
+		info, err := users.GetUserInfo(buddy)
+		if err != nil {
+			continue
+		}
+		offsets[buddy] = info.TZOffset
+	}
+
+	return offsets
+}
+
+// sameTimezone keeps the buddies closest to the mentee timezone
+// and breaks ties with the least loaded of them
+func (s *BuddyService) sameTimezone(candidates []string, mentee *slack.User, offsets map[string]int) string {
+	closest := []string{}
+	bestDistance := -1
+
+	for _, buddy := range candidates {
+		offset, ok := offsets[buddy]
+		if !ok {
+			continue
+		}
+
+		distance := offset - mentee.TZOffset
+		if distance < 0 {
+			distance = -distance
+		}
+
+		if bestDistance == -1 || distance < bestDistance {
+			closest = []string{buddy}
+			bestDistance = distance
+		} else if distance == bestDistance {
+			closest = append(closest, buddy)
+		}
+	}
+
+	// we were not able to get any timezone
+	if len(closest) == 0 {
+		return s.leastLoaded(candidates)
+	}
+
+	return s.leastLoaded(closest)
+}
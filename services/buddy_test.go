@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeUsers resolves users from a map instead of calling users.info
+type fakeUsers map[string]*slack.User
+
+func (f fakeUsers) GetUserInfo(user string) (*slack.User, error) {
+	if u, ok := f[user]; ok {
+		return u, nil
+	}
+	return nil, errors.New("user_not_found")
+}
+
+func TestLoadBuddyConfig(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "valid.json"), []byte(`{"pools":[{"channel":"C1","strategy":"least_loaded","buddies":["U1"]}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"pools":[{"channel":"C1","strategy":"random","buddies":["U1"]}]}`), 0644)
+
+	tests := []struct {
+		name      string
+		file      string
+		wantPools int
+		wantErr   bool
+	}{
+		{name: "No file", file: "", wantPools: 0},
+		{name: "Valid file", file: filepath.Join(dir, "valid.json"), wantPools: 1},
+		{name: "Unknown strategy", file: filepath.Join(dir, "invalid.json"), wantErr: true},
+		{name: "Missing file", file: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadBuddyConfig(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadBuddyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got.Pools) != tt.wantPools {
+				t.Errorf("LoadBuddyConfig() pools = %v, want %v", len(got.Pools), tt.wantPools)
+			}
+		})
+	}
+}
+
+// assign picks a buddy and records the pairing, as when the introduction succeeded
+func assign(s *BuddyService, channel string, mentee *slack.User, users UserInfoGetter) (Pairing, bool) {
+	pairing, ok := s.Pick(channel, mentee, users)
+	if !ok {
+		return pairing, false
+	}
+
+	return pairing, s.Commit(pairing)
+}
+
+func TestBuddyService_Pick(t *testing.T) {
+	users := fakeUsers{
+		"B1": {ID: "B1", TZOffset: 3600},
+		"B2": {ID: "B2", TZOffset: -18000},
+		"B3": {ID: "B3", TZOffset: -18000},
+	}
+
+	tests := []struct {
+		name     string
+		strategy BuddyStrategy
+		mentees  []*slack.User
+		want     []string
+	}{
+		{
+			name:     "Round robin cycles through the pool",
+			strategy: BuddyRoundRobin,
+			mentees:  []*slack.User{{ID: "M1"}, {ID: "M2"}, {ID: "M3"}, {ID: "M4"}},
+			want:     []string{"B1", "B2", "B3", "B1"},
+		},
+		{
+			name:     "Least loaded balances the mentees",
+			strategy: BuddyLeastLoaded,
+			mentees:  []*slack.User{{ID: "M1"}, {ID: "M2"}, {ID: "M3"}},
+			want:     []string{"B1", "B2", "B3"},
+		},
+		{
+			name:     "Same timezone picks the closest buddies",
+			strategy: BuddySameTimezone,
+			mentees:  []*slack.User{{ID: "M1", TZOffset: -14400}, {ID: "M2", TZOffset: -14400}, {ID: "M3", TZOffset: 7200}},
+			want:     []string{"B2", "B3", "B1"},
+		},
+		{
+			name:     "A buddy is never their own mentee",
+			strategy: BuddyRoundRobin,
+			mentees:  []*slack.User{{ID: "B1"}},
+			want:     []string{"B2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBuddyService(BuddyConfig{
+				Pools: []BuddyPool{
+					{Channel: "C1", Strategy: tt.strategy, Buddies: []string{"B1", "B2", "B3"}},
+				},
+			})
+
+			for i, mentee := range tt.mentees {
+				got, ok := assign(s, "C1", mentee, users)
+				if !ok {
+					t.Fatalf("Pick() no buddy for %v", mentee.ID)
+				}
+				if got.Buddy != tt.want[i] {
+					t.Errorf("Pick() buddy for %v = %v, want %v", mentee.ID, got.Buddy, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuddyService_Mentees(t *testing.T) {
+	s := NewBuddyService(BuddyConfig{
+		Pools: []BuddyPool{
+			{Channel: "C1", Strategy: BuddyRoundRobin, Buddies: []string{"B1"}},
+		},
+	})
+
+	// Channel without pool
+	if _, ok := s.Pick("C2", &slack.User{ID: "M1"}, fakeUsers{}); ok {
+		t.Errorf("Pick() should not pair members of a channel without pool")
+	}
+
+	assign(s, "C1", &slack.User{ID: "M1"}, fakeUsers{})
+	assign(s, "C1", &slack.User{ID: "M2"}, fakeUsers{})
+
+	// Joining twice does not create a second pairing
+	if _, ok := s.Pick("C1", &slack.User{ID: "M1"}, fakeUsers{}); ok {
+		t.Errorf("Pick() should not pair the same member twice")
+	}
+
+	// A pairing picked concurrently is not recorded twice
+	if s.Commit(Pairing{Buddy: "B1", Mentee: "M2", Channel: "C1"}) {
+		t.Errorf("Commit() should not pair the same member twice")
+	}
+
+	// A buddy who was picked but not introduced has no mentee
+	if _, ok := s.Pick("C1", &slack.User{ID: "M3"}, fakeUsers{}); !ok {
+		t.Errorf("Pick() no buddy for M3")
+	}
+
+	if got := len(s.Mentees("B1")); got != 2 {
+		t.Errorf("Mentees() = %v, want %v", got, 2)
+	}
+}
+
+// slowUsers reads the pairings of the service while resolving a user, like a slow users.info
+type slowUsers struct {
+	fakeUsers
+	service *BuddyService
+}
+
+func (f slowUsers) GetUserInfo(user string) (*slack.User, error) {
+	f.service.Mentees(user)
+	return f.fakeUsers.GetUserInfo(user)
+}
+
+func TestBuddyService_Pick_Unlocked(t *testing.T) {
+	s := NewBuddyService(BuddyConfig{
+		Pools: []BuddyPool{
+			{Channel: "C1", Strategy: BuddySameTimezone, Buddies: []string{"B1", "B2"}},
+		},
+	})
+	users := slowUsers{
+		fakeUsers: fakeUsers{"B1": {ID: "B1", TZOffset: 3600}, "B2": {ID: "B2", TZOffset: -18000}},
+		service:   s,
+	}
+
+	// The timezones are resolved without holding the lock of the service
+	done := make(chan Pairing)
+	go func() {
+		pairing, _ := s.Pick("C1", &slack.User{ID: "M1", TZOffset: -14400}, users)
+		done <- pairing
+	}()
+
+	select {
+	case pairing := <-done:
+		if pairing.Buddy != "B2" {
+			t.Errorf("Pick() buddy = %v, want B2", pairing.Buddy)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pick() holds the lock while resolving the timezones")
+	}
+}
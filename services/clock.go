@@ -0,0 +1,191 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts time so the services can be driven by a FakeClock in tests
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker is the part of time.Ticker used by the services
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is the part of time.Timer used by the services
+type Timer interface {
+	Stop() bool
+}
+
+// SystemClock is the real clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (SystemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// FakeClock only moves forward when Advance is called
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+
+	return t
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		at: c.now.Add(d),
+		f:  f,
+	}
+	c.timers = append(c.timers, t)
+
+	return t
+}
+
+// Advance moves the clock forward and fires the tickers and timers that are due
+// like time.Ticker, ticks are dropped when the previous one was not consumed
+// timer functions are called synchronously once the clock is updated
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer func() {
+		due := c.dueTimers()
+		c.mu.Unlock()
+
+		for _, t := range due {
+			t.f()
+		}
+	}()
+
+	c.now = c.now.Add(d)
+
+	for _, t := range c.tickers {
+		for !t.stopped() && !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.interval)
+		}
+	}
+}
+
+type fakeTicker struct {
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+
+	mu   sync.Mutex
+	stop bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stop = true
+}
+
+func (t *fakeTicker) stopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stop
+}
+
+// dueTimers removes the timers that must fire or were stopped from the clock
+func (c *FakeClock) dueTimers() []*fakeTimer {
+	due := []*fakeTimer{}
+	pending := []*fakeTimer{}
+
+	for _, t := range c.timers {
+		t.mu.Lock()
+		switch {
+		case t.done:
+		case t.at.After(c.now):
+			pending = append(pending, t)
+		default:
+			t.done = true
+			due = append(due, t)
+		}
+		t.mu.Unlock()
+	}
+	c.timers = pending
+
+	return due
+}
+
+type fakeTimer struct {
+	at time.Time
+	f  func()
+
+	mu   sync.Mutex
+	done bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stopped := !t.done
+	t.done = true
+
+	return stopped
+}
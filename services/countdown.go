@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CountdownTickFunc is called at every tick of a countdown, down to 0
+type CountdownTickFunc func(id string, remaining int)
+
+type countdown struct {
+	abort chan struct{}
+	done  chan struct{}
+}
+
+// CountdownService runs countdowns in the background
+// so they survive the handler that started them and can be aborted
+type CountdownService struct {
+	clock    Clock
+	interval time.Duration
+
+	mu         sync.Mutex
+	seq        int
+	countdowns map[string]*countdown
+}
+
+func NewCountdownService(clock Clock, interval time.Duration) *CountdownService {
+	return &CountdownService{
+		clock:      clock,
+		interval:   interval,
+		countdowns: make(map[string]*countdown),
+	}
+}
+
+// Start calls tick immediately with from, then once per interval until 0
+// It returns the ID used to abort the countdown
+func (s *CountdownService) Start(from int, tick CountdownTickFunc) string {
+	s.mu.Lock()
+	s.seq++
+	id := strconv.Itoa(s.seq)
+	cd := &countdown{
+		abort: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	s.countdowns[id] = cd
+	s.mu.Unlock()
+
+	// the ticker is created before returning so a FakeClock can drive it right away
+	ticker := s.clock.NewTicker(s.interval)
+
+	go func() {
+		defer close(cd.done)
+		defer ticker.Stop()
+		defer s.remove(id)
+
+		for remaining := from; remaining >= 0; remaining-- {
+			// an abort received while waiting wins over the tick
+			select {
+			case <-cd.abort:
+				return
+			default:
+			}
+
+			tick(id, remaining)
+
+			if remaining == 0 {
+				return
+			}
+
+			select {
+			case <-cd.abort:
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+
+	return id
+}
+
+// Abort stops a running countdown
+// It returns once the last tick is done, false if the countdown is not running
+func (s *CountdownService) Abort(id string) bool {
+	s.mu.Lock()
+	cd, ok := s.countdowns[id]
+	if ok {
+		delete(s.countdowns, id)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	close(cd.abort)
+	<-cd.done
+
+	return true
+}
+
+// Running lists the IDs of the countdowns in progress
+func (s *CountdownService) Running() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []string{}
+	for id := range s.countdowns {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// Wait returns once the running countdowns are over or when ctx is done
+func (s *CountdownService) Wait(ctx context.Context) error {
+	s.mu.Lock()
+	running := []*countdown{}
+	for _, cd := range s.countdowns {
+		running = append(running, cd)
+	}
+	s.mu.Unlock()
+
+	for _, cd := range running {
+		select {
+		case <-cd.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (s *CountdownService) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.countdowns, id)
+}
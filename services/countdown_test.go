@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// nextTick waits for the next tick of a count down
+func nextTick(t *testing.T, ticks chan int) int {
+	t.Helper()
+
+	select {
+	case remaining := <-ticks:
+		return remaining
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for a tick")
+	}
+
+	return -1
+}
+
+func TestCountdownService_Start(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	s := NewCountdownService(clock, time.Second)
+
+	ticks := make(chan int)
+	s.Start(3, func(id string, remaining int) {
+		ticks <- remaining
+	})
+
+	// The first tick is immediate
+	if got := nextTick(t, ticks); got != 3 {
+		t.Errorf("tick = %v, want %v", got, 3)
+	}
+
+	// Then one tick per second
+	for want := 2; want >= 0; want-- {
+		clock.Advance(time.Second)
+		if got := nextTick(t, ticks); got != want {
+			t.Errorf("tick = %v, want %v", got, want)
+		}
+	}
+
+	// The finished count down can not be aborted
+	time.Sleep(10 * time.Millisecond)
+	if running := s.Running(); len(running) != 0 {
+		t.Errorf("Running() = %v, want none", running)
+	}
+}
+
+func TestCountdownService_Abort(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	s := NewCountdownService(clock, time.Second)
+
+	ticks := make(chan int, 10)
+	id := s.Start(10, func(id string, remaining int) {
+		ticks <- remaining
+	})
+
+	nextTick(t, ticks)
+	clock.Advance(time.Second)
+	nextTick(t, ticks)
+
+	if !s.Abort(id) {
+		t.Fatalf("Abort(%v) = false, want true", id)
+	}
+
+	// No tick once aborted
+	clock.Advance(time.Second)
+	select {
+	case remaining := <-ticks:
+		t.Errorf("unexpected tick %v after abort", remaining)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if s.Abort(id) {
+		t.Errorf("Abort(%v) twice = true, want false", id)
+	}
+}
+
+func TestCountdownService_Wait(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	s := NewCountdownService(clock, time.Second)
+
+	ticks := make(chan int, 10)
+	s.Start(1, func(id string, remaining int) {
+		ticks <- remaining
+	})
+	nextTick(t, ticks)
+
+	// The count down is not over yet
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() = %v, want context.DeadlineExceeded", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := s.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+}
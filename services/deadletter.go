@@ -0,0 +1,212 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrDeadLetterNotFound = errors.New("no dead letter with this ID")
+
+// DefaultDeadLetterLimit bounds the memory used by the failed events, the oldest are dropped
+const DefaultDeadLetterLimit = 1000
+
+// DeadLetter is an event whose handler failed, kept to be inspected and replayed
+type DeadLetter struct {
+	// ID is the correlation ID of the failed request, found in the logs
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Event summarizes the event, e.g. `events_api app_home_opened`
+	Event string `json:"event"`
+	// Envelope is the message received on the socket, the event is rebuilt from it
+	Envelope json.RawMessage `json:"envelope"`
+	// Handler is the name of the handler that failed
+	Handler  string `json:"handler"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+}
+
+// DeadLetterStore keeps the failed events until they are replayed or purged
+type DeadLetterStore interface {
+	// Put saves a dead letter, replacing the one with the same ID
+	Put(letter DeadLetter) error
+	// Get returns ErrDeadLetterNotFound when the ID is unknown
+	Get(id string) (DeadLetter, error)
+	// List returns the dead letters from the oldest
+	List() ([]DeadLetter, error)
+	// Delete forgets a dead letter, e.g. once it is replayed
+	Delete(id string) error
+	// Purge forgets every dead letter and tells how many there were
+	Purge() (int, error)
+}
+
+// MemoryDeadLetterStore keeps up to limit dead letters in memory
+type MemoryDeadLetterStore struct {
+	limit int
+
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+func NewMemoryDeadLetterStore(limit int) *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{
+		limit:   limit,
+		letters: make(map[string]DeadLetter),
+	}
+}
+
+func (s *MemoryDeadLetterStore) Put(letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters[letter.ID] = letter
+
+	// the oldest are dropped
+	if s.limit > 0 && len(s.letters) > s.limit {
+		for _, old := range s.sorted()[:len(s.letters)-s.limit] {
+			delete(s.letters, old.ID)
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryDeadLetterStore) Get(id string) (DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letter, ok := s.letters[id]
+	if !ok {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+
+	return letter, nil
+}
+
+func (s *MemoryDeadLetterStore) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted(), nil
+}
+
+func (s *MemoryDeadLetterStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(s.letters, id)
+
+	return nil
+}
+
+func (s *MemoryDeadLetterStore) Purge() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.letters)
+	s.letters = make(map[string]DeadLetter)
+
+	return count, nil
+}
+
+// sorted lists the dead letters from the oldest, the store must be locked
+func (s *MemoryDeadLetterStore) sorted() []DeadLetter {
+	letters := []DeadLetter{}
+	for _, letter := range s.letters {
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].Time.Equal(letters[j].Time) {
+			return letters[i].ID < letters[j].ID
+		}
+		return letters[i].Time.Before(letters[j].Time)
+	})
+
+	return letters
+}
+
+// FileDeadLetterStore keeps the dead letters in memory and saves them in a JSON file on every change
+// so the failed events survive a restart or a crash
+type FileDeadLetterStore struct {
+	*MemoryDeadLetterStore
+	file string
+
+	// saving keeps the file in the order of the changes
+	saving sync.Mutex
+}
+
+// NewFileDeadLetterStore loads the dead letters saved in file, a missing file is an empty store
+func NewFileDeadLetterStore(limit int, file string) (*FileDeadLetterStore, error) {
+	s := &FileDeadLetterStore{
+		MemoryDeadLetterStore: NewMemoryDeadLetterStore(limit),
+		file:                  file,
+	}
+
+	str, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	letters := []DeadLetter{}
+	if err := json.Unmarshal(str, &letters); err != nil {
+		return s, fmt.Errorf("invalid dead letter store %s: %w", file, err)
+	}
+
+	for _, letter := range letters {
+		s.MemoryDeadLetterStore.Put(letter)
+	}
+
+	return s, nil
+}
+
+func (s *FileDeadLetterStore) Put(letter DeadLetter) error {
+	if err := s.MemoryDeadLetterStore.Put(letter); err != nil {
+		return err
+	}
+
+	return s.Flush()
+}
+
+func (s *FileDeadLetterStore) Delete(id string) error {
+	if err := s.MemoryDeadLetterStore.Delete(id); err != nil {
+		return err
+	}
+
+	return s.Flush()
+}
+
+func (s *FileDeadLetterStore) Purge() (int, error) {
+	count, err := s.MemoryDeadLetterStore.Purge()
+	if err != nil {
+		return count, err
+	}
+
+	return count, s.Flush()
+}
+
+// Flush saves the dead letters
+func (s *FileDeadLetterStore) Flush() error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+
+	letters, _ := s.List()
+
+	str, err := json.MarshalIndent(letters, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.file, str)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestMemoryDeadLetterStore(t *testing.T) {
+	start := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryDeadLetterStore(2)
+
+	for i, id := range []string{"a", "b", "c"} {
+		store.Put(DeadLetter{ID: id, Time: start.Add(time.Duration(i) * time.Minute), Attempts: 1})
+	}
+
+	// The oldest is dropped
+	if _, err := store.Get("a"); err != ErrDeadLetterNotFound {
+		t.Errorf("got %v, want ErrDeadLetterNotFound", err)
+	}
+
+	// A replay updates the dead letter
+	store.Put(DeadLetter{ID: "b", Time: start.Add(time.Minute), Attempts: 4, Error: "internal_error"})
+
+	letters, _ := store.List()
+	want := []DeadLetter{
+		{ID: "b", Time: start.Add(time.Minute), Attempts: 4, Error: "internal_error"},
+		{ID: "c", Time: start.Add(2 * time.Minute), Attempts: 1},
+	}
+	if diff := deep.Equal(letters, want); diff != nil {
+		t.Error(diff)
+	}
+
+	if err := store.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("b"); err != ErrDeadLetterNotFound {
+		t.Errorf("got %v, want ErrDeadLetterNotFound", err)
+	}
+
+	count, _ := store.Purge()
+	if count != 1 {
+		t.Errorf("%d dead letters purged, want 1", count)
+	}
+	if letters, _ := store.List(); len(letters) != 0 {
+		t.Errorf("%d dead letters left after the purge", len(letters))
+	}
+}
+
+func TestFileDeadLetterStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deadletters.json")
+
+	store, err := NewFileDeadLetterStore(DefaultDeadLetterLimit, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	letter := DeadLetter{
+		ID:       "b415ea3c",
+		Time:     time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC),
+		Event:    "events_api app_home_opened",
+		Envelope: json.RawMessage(`{"envelope_id":"e1","type":"events_api"}`),
+		Handler:  "controllers.(*AppHomeController).publishHomeTabView",
+		Error:    "internal_error",
+		Attempts: 3,
+	}
+	if err := store.Put(letter); err != nil {
+		t.Fatal(err)
+	}
+	store.Put(DeadLetter{ID: "c2d1f0e9"})
+	if err := store.Delete("c2d1f0e9"); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a crash, the store was never flushed
+	restarted, err := NewFileDeadLetterStore(DefaultDeadLetterLimit, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := restarted.Get("b415ea3c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the file is indented
+	compact := &bytes.Buffer{}
+	json.Compact(compact, got.Envelope)
+	got.Envelope = compact.Bytes()
+
+	if diff := deep.Equal(got, letter); diff != nil {
+		t.Error(diff)
+	}
+
+	if _, err := restarted.Get("c2d1f0e9"); err != ErrDeadLetterNotFound {
+		t.Errorf("the deleted dead letter is back: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// DefaultDirectoryTTL is how long users and channels are kept in cache
+const DefaultDirectoryTTL = 15 * time.Minute
+
+// DirectoryAPI is the part of the slack API used by the Directory
+type DirectoryAPI interface {
+	GetUserInfo(user string) (*slack.User, error)
+	GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error)
+	GetUsersPaginated(options ...slack.GetUsersOption) slack.UserPagination
+}
+
+type cachedUser struct {
+	user    *slack.User
+	expires time.Time
+}
+
+type cachedChannel struct {
+	channel *slack.Channel
+	expires time.Time
+}
+
+// Directory is a cache in front of users.info and conversations.info
+type Directory struct {
+	api DirectoryAPI
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.RWMutex
+	users    map[string]cachedUser
+	channels map[string]cachedChannel
+}
+
+func NewDirectory(api DirectoryAPI, ttl time.Duration) *Directory {
+	return &Directory{
+		api:      api,
+		ttl:      ttl,
+		now:      time.Now,
+		users:    make(map[string]cachedUser),
+		channels: make(map[string]cachedChannel),
+	}
+}
+
+// GetUserInfo returns the user from the cache or calls users.info
+// It can be used in place of slack.Client.GetUserInfo
+func (d *Directory) GetUserInfo(user string) (*slack.User, error) {
+	d.mu.RLock()
+	cached, ok := d.users[user]
+	d.mu.RUnlock()
+
+	if ok && d.now().Before(cached.expires) {
+		return cached.user, nil
+	}
+
+	info, err := d.api.GetUserInfo(user)
+	if err != nil {
+		return nil, err
+	}
+
+	d.SetUser(*info)
+
+	return info, nil
+}
+
+// GetConversationInfo returns the channel from the cache or calls conversations.info
+func (d *Directory) GetConversationInfo(channel string) (*slack.Channel, error) {
+	d.mu.RLock()
+	cached, ok := d.channels[channel]
+	d.mu.RUnlock()
+
+	if ok && d.now().Before(cached.expires) {
+		return cached.channel, nil
+	}
+
+	info, err := d.api.GetConversationInfo(channel, false)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.channels[channel] = cachedChannel{channel: info, expires: d.now().Add(d.ttl)}
+	d.mu.Unlock()
+
+	return info, nil
+}
+
+// UserName returns the user name or a mention when the user cannot be found
+func (d *Directory) UserName(user string) string {
+	info, err := d.GetUserInfo(user)
+	if err != nil || info.Name == "" {
+		return "<@" + user + ">"
+	}
+
+	return info.Name
+}
+
+// ChannelName returns the channel name or a link when the channel cannot be found
+func (d *Directory) ChannelName(channel string) string {
+	info, err := d.GetConversationInfo(channel)
+	if err != nil || info.Name == "" {
+		return "<#" + channel + ">"
+	}
+
+	return info.Name
+}
+
+// SetUser refreshes a user, e.g. when we receive a `user_change` event
+func (d *Directory) SetUser(user slack.User) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.users[user.ID] = cachedUser{user: &user, expires: d.now().Add(d.ttl)}
+}
+
+// InvalidateUser forces the next lookup to call users.info
+func (d *Directory) InvalidateUser(user string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.users, user)
+}
+
+// InvalidateChannel forces the next lookup to call conversations.info
+func (d *Directory) InvalidateChannel(channel string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.channels, channel)
+}
+
+// WarmUp loads every user of the workspace using users.list pagination
+func (d *Directory) WarmUp(ctx context.Context) error {
+	var err error
+
+	p := d.api.GetUsersPaginated(slack.GetUsersOptionLimit(200))
+	for err == nil {
+		p, err = p.Next(ctx)
+		if err == nil {
+			for _, user := range p.Users {
+				d.SetUser(user)
+			}
+		} else if rateLimited, ok := err.(*slack.RateLimitedError); ok {
+			// wait before fetching the next page
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(rateLimited.RetryAfter):
+				err = nil
+			}
+		}
+	}
+
+	return p.Failure(err)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// setupDirectoryAPI serves users.info, conversations.info and users.list
+// U404 and C404 do not exist
+func setupDirectoryAPI(t *testing.T, calls *int32) *slack.Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.FormValue("user") == "U404" {
+			w.Write([]byte(`{"ok":false,"error":"user_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"user":{"id":"` + r.FormValue("user") + `","name":"david"}}`))
+	})
+	mux.HandleFunc("/conversations.info", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.FormValue("channel") == "C404" {
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"channel":{"id":"` + r.FormValue("channel") + `","name":"general"}}`))
+	})
+	mux.HandleFunc("/users.list", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.FormValue("cursor") == "" {
+			w.Write([]byte(`{"ok":true,"members":[{"id":"U1","name":"one"}],"response_metadata":{"next_cursor":"page2"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"members":[{"id":"U2","name":"two"}],"response_metadata":{"next_cursor":""}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return slack.New("ABCD", slack.OptionAPIURL(server.URL+"/"))
+}
+
+func TestDirectory_GetUserInfo(t *testing.T) {
+	var calls int32
+	d := NewDirectory(setupDirectoryAPI(t, &calls), time.Minute)
+
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	// First lookup calls the API
+	if _, err := d.GetUserInfo("U1"); err != nil {
+		t.Fatal(err)
+	}
+	// Second lookup is cached
+	d.GetUserInfo("U1")
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("API calls = %v, want %v", got, 1)
+	}
+
+	// Expired entries are fetched again
+	now = now.Add(2 * time.Minute)
+	d.GetUserInfo("U1")
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("API calls = %v, want %v", got, 2)
+	}
+
+	// Invalidated entries are fetched again
+	d.InvalidateUser("U1")
+	d.GetUserInfo("U1")
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("API calls = %v, want %v", got, 3)
+	}
+}
+
+func TestDirectory_Names(t *testing.T) {
+	var calls int32
+	d := NewDirectory(setupDirectoryAPI(t, &calls), time.Minute)
+
+	tests := []struct {
+		name string
+		got  func() string
+		want string
+	}{
+		{name: "Known user", got: func() string { return d.UserName("U1") }, want: "david"},
+		{name: "Unknown user", got: func() string { return d.UserName("U404") }, want: "<@U404>"},
+		{name: "Known channel", got: func() string { return d.ChannelName("C1") }, want: "general"},
+		{name: "Unknown channel", got: func() string { return d.ChannelName("C404") }, want: "<#C404>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirectory_SetUser(t *testing.T) {
+	var calls int32
+	d := NewDirectory(setupDirectoryAPI(t, &calls), time.Minute)
+
+	// e.g. a `user_change` event
+	d.SetUser(slack.User{ID: "U1", Name: "renamed"})
+
+	if got := d.UserName("U1"); got != "renamed" {
+		t.Errorf("UserName() = %v, want %v", got, "renamed")
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("API calls = %v, want %v", got, 0)
+	}
+}
+
+func TestDirectory_WarmUp(t *testing.T) {
+	var calls int32
+	d := NewDirectory(setupDirectoryAPI(t, &calls), time.Minute)
+
+	if err := d.WarmUp(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both pages are loaded
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("API calls = %v, want %v", got, 2)
+	}
+
+	// Users are served from the cache
+	if got := d.UserName("U2"); got != "two" {
+		t.Errorf("UserName() = %v, want %v", got, "two")
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("API calls = %v, want %v", got, 2)
+	}
+}
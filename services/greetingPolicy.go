@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/slack-go/slack"
+)
+
+// GreetingMatch describes the profile of the joiners a rule applies to
+// Unset fields match every joiner
+type GreetingMatch struct {
+	Bot             *bool `json:"is_bot,omitempty"`
+	Restricted      *bool `json:"is_restricted,omitempty"`
+	UltraRestricted *bool `json:"is_ultra_restricted,omitempty"`
+	// External matches users from another organization (Slack Connect)
+	External *bool    `json:"external,omitempty"`
+	Teams    []string `json:"teams,omitempty"`
+}
+
+// GreetingRule selects a template for matching joiners or suppresses the greeting
+type GreetingRule struct {
+	Match    GreetingMatch `json:"match"`
+	Skip     bool          `json:"skip,omitempty"`
+	Template string        `json:"template,omitempty"`
+}
+
+// GreetingPolicy is the list of rules evaluated when a member joins a channel
+// Channel rules are evaluated before the default rules, the first match wins
+type GreetingPolicy struct {
+	Channels map[string][]GreetingRule `json:"channels"`
+	Default  []GreetingRule            `json:"default"`
+}
+
+// GreetingDecision is the outcome of the policy for a joiner
+// An empty template means the default greeting
+type GreetingDecision struct {
+	Skip     bool
+	Template string
+}
+
+// DefaultGreetingPolicy skips bots and welcomes guests and external users differently
+func DefaultGreetingPolicy() GreetingPolicy {
+	yes := true
+
+	return GreetingPolicy{
+		Default: []GreetingRule{
+			{Match: GreetingMatch{Bot: &yes}, Skip: true},
+			{Match: GreetingMatch{External: &yes}, Template: "greeting_external.json"},
+			{Match: GreetingMatch{UltraRestricted: &yes}, Template: "greeting_guest.json"},
+			{Match: GreetingMatch{Restricted: &yes}, Template: "greeting_guest.json"},
+		},
+	}
+}
+
+// LoadGreetingPolicy reads the greeting policy file
+// When no file is provided we use the DefaultGreetingPolicy
+func LoadGreetingPolicy(file string) (GreetingPolicy, error) {
+	if file == "" {
+		return DefaultGreetingPolicy(), nil
+	}
+
+	policy := GreetingPolicy{}
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return policy, err
+	}
+
+	err = json.Unmarshal(str, &policy)
+
+	return policy, err
+}
+
+// Templates lists every template referenced by the policy
+func (p GreetingPolicy) Templates() []string {
+	templates := []string{}
+
+	add := func(rules []GreetingRule) {
+		for _, rule := range rules {
+			if rule.Template != "" {
+				templates = append(templates, rule.Template)
+			}
+		}
+	}
+
+	for _, rules := range p.Channels {
+		add(rules)
+	}
+	add(p.Default)
+
+	return templates
+}
+
+// Evaluate finds the rule matching the joiner of a channel
+// homeTeam is the workspace the App is installed in
+func (p GreetingPolicy) Evaluate(channel string, homeTeam string, user *slack.User) GreetingDecision {
+	// Without a profile we cannot match anything
+	if user == nil {
+		return GreetingDecision{}
+	}
+
+	rules := append(append([]GreetingRule{}, p.Channels[channel]...), p.Default...)
+
+	for _, rule := range rules {
+		if rule.Match.matches(homeTeam, user) {
+			return GreetingDecision{Skip: rule.Skip, Template: rule.Template}
+		}
+	}
+
+	return GreetingDecision{}
+}
+
+func (m GreetingMatch) matches(homeTeam string, user *slack.User) bool {
+	if m.Bot != nil && *m.Bot != user.IsBot {
+		return false
+	}
+
+	if m.Restricted != nil && *m.Restricted != user.IsRestricted {
+		return false
+	}
+
+	if m.UltraRestricted != nil && *m.UltraRestricted != user.IsUltraRestricted {
+		return false
+	}
+
+	if m.External != nil {
+		external := homeTeam != "" && user.TeamID != "" && user.TeamID != homeTeam
+		if *m.External != external {
+			return false
+		}
+	}
+
+	if len(m.Teams) > 0 {
+		found := false
+		for _, team := range m.Teams {
+			if team == user.TeamID {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
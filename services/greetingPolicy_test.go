@@ -0,0 +1,114 @@
+package services
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+)
+
+func TestGreetingPolicy_Evaluate(t *testing.T) {
+	yes := true
+	no := false
+
+	policy := DefaultGreetingPolicy()
+	policy.Channels = map[string][]GreetingRule{
+		// Guests are not greeted in the announcement channel
+		"CANNOUNCE": {
+			{Match: GreetingMatch{Restricted: &yes}, Skip: true},
+		},
+		// Partners get a dedicated message
+		"CPARTNERS": {
+			{Match: GreetingMatch{Teams: []string{"TPARTNER"}, Bot: &no}, Template: "partner.json"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		user    *slack.User
+		want    GreetingDecision
+	}{
+		{
+			name:    "Members get the default greeting",
+			channel: "CGENERAL",
+			user:    &slack.User{ID: "U1", TeamID: "THOME"},
+			want:    GreetingDecision{},
+		},
+		{
+			name:    "Bots are skipped",
+			channel: "CGENERAL",
+			user:    &slack.User{ID: "B1", TeamID: "THOME", IsBot: true},
+			want:    GreetingDecision{Skip: true},
+		},
+		{
+			name:    "Guests get the guest template",
+			channel: "CGENERAL",
+			user:    &slack.User{ID: "U2", TeamID: "THOME", IsRestricted: true, IsUltraRestricted: true},
+			want:    GreetingDecision{Template: "greeting_guest.json"},
+		},
+		{
+			name:    "External users get the external template",
+			channel: "CGENERAL",
+			user:    &slack.User{ID: "U3", TeamID: "TOTHER"},
+			want:    GreetingDecision{Template: "greeting_external.json"},
+		},
+		{
+			name:    "Channel rules are evaluated first",
+			channel: "CANNOUNCE",
+			user:    &slack.User{ID: "U2", TeamID: "THOME", IsRestricted: true},
+			want:    GreetingDecision{Skip: true},
+		},
+		{
+			name:    "Channel rules can match a team",
+			channel: "CPARTNERS",
+			user:    &slack.User{ID: "U4", TeamID: "TPARTNER"},
+			want:    GreetingDecision{Template: "partner.json"},
+		},
+		{
+			name:    "Unknown profile gets the default greeting",
+			channel: "CGENERAL",
+			user:    nil,
+			want:    GreetingDecision{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := deep.Equal(policy.Evaluate(tt.channel, "THOME", tt.user), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLoadGreetingPolicy(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "policy.json"), []byte(`{"channels":{"C1":[{"match":{"is_bot":true},"template":"bots.json"}]},"default":[{"match":{"is_restricted":true},"skip":true}]}`), 0644)
+
+	tests := []struct {
+		name          string
+		file          string
+		wantTemplates []string
+		wantErr       bool
+	}{
+		{name: "Default policy", file: "", wantTemplates: []string{"greeting_external.json", "greeting_guest.json", "greeting_guest.json"}},
+		{name: "Policy file", file: filepath.Join(dir, "policy.json"), wantTemplates: []string{"bots.json"}},
+		{name: "Missing file", file: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadGreetingPolicy(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadGreetingPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := deep.Equal(got.Templates(), tt.wantTemplates); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
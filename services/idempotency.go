@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL covers the retries of slack, the last one comes about 5 minutes after the event
+const DefaultIdempotencyTTL = time.Hour
+
+// IdempotencyStore remembers which delivery of an event is handled
+// e.g. slack redelivers an event with a new envelope when it was acknowledged late
+type IdempotencyStore interface {
+	// Claim records the owner of a key unless another owner claimed it already
+	// it tells if the owner can handle the event
+	Claim(key string, owner string) (bool, error)
+	// Release forgets a key so the event can be handled again, e.g. when its handler failed
+	Release(key string) error
+}
+
+// IdempotencyClaim is who claimed a key and until when
+type IdempotencyClaim struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// MemoryIdempotencyStore keeps the claims in memory for ttl
+type MemoryIdempotencyStore struct {
+	clock Clock
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	claims map[string]IdempotencyClaim
+}
+
+func NewMemoryIdempotencyStore(clock Clock, ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		clock:  clock,
+		ttl:    ttl,
+		claims: make(map[string]IdempotencyClaim),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Claim(key string, owner string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.prune(now)
+
+	if claim, ok := s.claims[key]; ok {
+		return claim.Owner == owner, nil
+	}
+
+	s.claims[key] = IdempotencyClaim{Owner: owner, Expires: now.Add(s.ttl)}
+
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claims, key)
+
+	return nil
+}
+
+// prune forgets the expired claims, the store must be locked
+func (s *MemoryIdempotencyStore) prune(now time.Time) {
+	for key, claim := range s.claims {
+		if !now.Before(claim.Expires) {
+			delete(s.claims, key)
+		}
+	}
+}
+
+// FileIdempotencyStore keeps the claims in memory and saves them in a JSON file on every change
+// so the events handled before a restart or a crash are not handled again
+type FileIdempotencyStore struct {
+	*MemoryIdempotencyStore
+	file string
+
+	// saving keeps the file in the order of the changes
+	saving sync.Mutex
+}
+
+// NewFileIdempotencyStore loads the claims saved in file, a missing file is an empty store
+func NewFileIdempotencyStore(clock Clock, ttl time.Duration, file string) (*FileIdempotencyStore, error) {
+	s := &FileIdempotencyStore{
+		MemoryIdempotencyStore: NewMemoryIdempotencyStore(clock, ttl),
+		file:                   file,
+	}
+
+	str, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(str, &s.claims); err != nil {
+		return s, fmt.Errorf("invalid idempotency store %s: %w", file, err)
+	}
+
+	return s, nil
+}
+
+// Claim saves a new claim before the event is handled
+func (s *FileIdempotencyStore) Claim(key string, owner string) (bool, error) {
+	first, err := s.MemoryIdempotencyStore.Claim(key, owner)
+	if err != nil || !first {
+		return first, err
+	}
+
+	return true, s.Flush()
+}
+
+func (s *FileIdempotencyStore) Release(key string) error {
+	if err := s.MemoryIdempotencyStore.Release(key); err != nil {
+		return err
+	}
+
+	return s.Flush()
+}
+
+// Flush saves the claims that did not expire
+func (s *FileIdempotencyStore) Flush() error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+
+	s.mu.Lock()
+	s.prune(s.clock.Now())
+	str, err := json.MarshalIndent(s.claims, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.file, str)
+}
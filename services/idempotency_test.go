@@ -0,0 +1,67 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryIdempotencyStore(clock, time.Hour)
+
+	claim := func(key string, owner string, want bool) {
+		t.Helper()
+		got, err := store.Claim(key, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Claim(%s, %s) = %v, want %v", key, owner, got, want)
+		}
+	}
+
+	claim("event:Ev1", "envelope-1", true)
+	// Another handler of the same delivery
+	claim("event:Ev1", "envelope-1", true)
+	// The retry of slack
+	claim("event:Ev1", "envelope-2", false)
+
+	// A released event is handled again
+	store.Release("event:Ev1")
+	claim("event:Ev1", "envelope-2", true)
+
+	// Claims expire
+	clock.Advance(time.Hour)
+	claim("event:Ev1", "envelope-3", true)
+}
+
+func TestFileIdempotencyStore(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+	file := filepath.Join(t.TempDir(), "idempotency.json")
+
+	store, err := NewFileIdempotencyStore(clock, time.Hour, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Claim("event:Ev1", "envelope-1"); err != nil {
+		t.Fatal(err)
+	}
+	store.Claim("event:Ev2", "envelope-2")
+	if err := store.Release("event:Ev2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a crash, the store was never flushed
+	restarted, err := NewFileIdempotencyStore(clock, time.Hour, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := restarted.Claim("event:Ev1", "envelope-2"); ok {
+		t.Error("the event handled before the restart is handled again")
+	}
+	if ok, _ := restarted.Claim("event:Ev2", "envelope-3"); !ok {
+		t.Error("the released event is not handled")
+	}
+}
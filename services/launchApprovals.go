@@ -0,0 +1,268 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLaunchRequestNotFound  = errors.New("this launch request was already decided")
+	ErrLaunchRequestExpired   = errors.New("this launch request expired")
+	ErrRequesterCannotApprove = errors.New("you cannot approve your own launch")
+	ErrNotAnApprover          = errors.New("you are not allowed to approve launches")
+	ErrAlreadyApproved        = errors.New("you already approved this launch")
+	ErrWrongChannel           = errors.New("this launch request belongs to another channel")
+)
+
+// ApprovalPolicy defines who must approve a launch before the count down starts
+// Without users nor user groups anybody in the channel can approve
+type ApprovalPolicy struct {
+	Approvers      int      `json:"approvers"`
+	AllowRequester bool     `json:"allow_requester"`
+	Users          []string `json:"users,omitempty"`
+	UserGroups     []string `json:"user_groups,omitempty"`
+	// ExpiryMinutes is how long a launch request waits for its approvals
+	ExpiryMinutes int `json:"expiry_minutes"`
+}
+
+// DefaultApprovalPolicy lets the requester approve their own launch
+func DefaultApprovalPolicy() ApprovalPolicy {
+	return ApprovalPolicy{
+		Approvers:      1,
+		AllowRequester: true,
+		ExpiryMinutes:  60,
+	}
+}
+
+// LoadApprovalPolicy reads the approval policy file
+// When no file is provided we use the DefaultApprovalPolicy
+func LoadApprovalPolicy(file string) (ApprovalPolicy, error) {
+	if file == "" {
+		return DefaultApprovalPolicy(), nil
+	}
+
+	policy := DefaultApprovalPolicy()
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return policy, err
+	}
+
+	if err := json.Unmarshal(str, &policy); err != nil {
+		return policy, err
+	}
+
+	if policy.Approvers < 1 {
+		return policy, fmt.Errorf("a launch needs at least 1 approver, got %d", policy.Approvers)
+	}
+	if policy.ExpiryMinutes < 1 {
+		return policy, fmt.Errorf("launch requests must be valid at least 1 minute, got %d", policy.ExpiryMinutes)
+	}
+
+	return policy, nil
+}
+
+// Shared is true when somebody else than the requester has to approve
+// or when only some users can approve, the announcement must then be visible in the channel
+func (p ApprovalPolicy) Shared() bool {
+	return p.Approvers > 1 || !p.AllowRequester || len(p.Users) > 0 || len(p.UserGroups) > 0
+}
+
+// UserGroupMembersGetter is the part of the slack API we need to check user groups
+type UserGroupMembersGetter interface {
+	GetUserGroupMembers(userGroup string) ([]string, error)
+}
+
+// LaunchRequest is a launch waiting for its approvals
+type LaunchRequest struct {
+	ID        string
+	Requester string
+	Channel   string
+	Approvers []string
+	// Rocket is the rocket the approvers agreed on
+	Rocket  string
+	Created time.Time
+}
+
+// LaunchApprovals keeps the launch requests until the quorum is met
+type LaunchApprovals struct {
+	policy ApprovalPolicy
+	groups UserGroupMembersGetter
+	clock  Clock
+
+	mu       sync.Mutex
+	requests map[string]LaunchRequest
+}
+
+func NewLaunchApprovals(policy ApprovalPolicy, groups UserGroupMembersGetter, clock Clock) *LaunchApprovals {
+	return &LaunchApprovals{
+		policy:   policy,
+		groups:   groups,
+		clock:    clock,
+		requests: make(map[string]LaunchRequest),
+	}
+}
+
+// Policy returns the approval policy in use
+func (s *LaunchApprovals) Policy() ApprovalPolicy {
+	return s.policy
+}
+
+// Open creates a launch request waiting for approvals
+func (s *LaunchApprovals) Open(requester string, channel string) LaunchRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+
+	request := LaunchRequest{
+		ID:        newRequestID(),
+		Requester: requester,
+		Channel:   channel,
+		Approvers: []string{},
+		Created:   s.clock.Now(),
+	}
+	s.requests[request.ID] = request
+
+	return request
+}
+
+// newRequestID is random so the buttons posted before a restart never match a new request
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Approve adds the approval of a user for a rocket to a launch request
+// the approval must be given in the channel of the request
+// the approvals given for another rocket are reset, they do not count for the new one
+// It returns true once the quorum is met, the request is then closed
+func (s *LaunchApprovals) Approve(id string, user string, channel string, rocket string) (LaunchRequest, bool, error) {
+	// The user groups are checked before locking as it calls the slack API
+	allowed, err := s.allowed(user)
+	if err != nil {
+		return LaunchRequest{}, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.requests[id]
+	if !ok {
+		return request, false, ErrLaunchRequestNotFound
+	}
+
+	if channel != request.Channel {
+		return LaunchRequest{}, false, ErrWrongChannel
+	}
+
+	if s.expired(request) {
+		delete(s.requests, id)
+		return request, false, ErrLaunchRequestExpired
+	}
+
+	if user == request.Requester && !s.policy.AllowRequester {
+		return request, false, ErrRequesterCannotApprove
+	}
+
+	if !allowed {
+		return request, false, ErrNotAnApprover
+	}
+
+	if rocket != request.Rocket {
+		request.Approvers = []string{}
+		request.Rocket = rocket
+	}
+
+	for _, approver := range request.Approvers {
+		if approver == user {
+			return request, false, ErrAlreadyApproved
+		}
+	}
+
+	request.Approvers = append(append([]string{}, request.Approvers...), user)
+
+	if len(request.Approvers) >= s.policy.Approvers {
+		delete(s.requests, id)
+		return request, true, nil
+	}
+
+	s.requests[id] = request
+
+	return request, false, nil
+}
+
+// Close removes a launch request, e.g. when it is denied
+func (s *LaunchApprovals) Close(id string) (LaunchRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.requests[id]
+	delete(s.requests, id)
+
+	return request, ok
+}
+
+// Pending returns the launch requests still waiting for approvals
+func (s *LaunchApprovals) Pending() []LaunchRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+
+	requests := []LaunchRequest{}
+	for _, request := range s.requests {
+		requests = append(requests, request)
+	}
+
+	return requests
+}
+
+func (s *LaunchApprovals) allowed(user string) (bool, error) {
+	if len(s.policy.Users) == 0 && len(s.policy.UserGroups) == 0 {
+		return true, nil
+	}
+
+	for _, u := range s.policy.Users {
+		if u == user {
+			return true, nil
+		}
+	}
+
+	for _, group := range s.policy.UserGroups {
+		members, err := s.groups.GetUserGroupMembers(group)
+		if err != nil {
+			return false, err
+		}
+
+		for _, member := range members {
+			if member == user {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (s *LaunchApprovals) expired(request LaunchRequest) bool {
+	expiry := time.Duration(s.policy.ExpiryMinutes) * time.Minute
+
+	return !s.clock.Now().Before(request.Created.Add(expiry))
+}
+
+// expire forgets the stale requests
+func (s *LaunchApprovals) expire() {
+	for id, request := range s.requests {
+		if s.expired(request) {
+			delete(s.requests, id)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+type fakeUserGroups map[string][]string
+
+func (g fakeUserGroups) GetUserGroupMembers(userGroup string) ([]string, error) {
+	return g[userGroup], nil
+}
+
+func TestLaunchApprovals_Approve(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+
+	policy := ApprovalPolicy{
+		Approvers:     2,
+		Users:         []string{"UREQUESTER", "UALICE"},
+		UserGroups:    []string{"SLAUNCH"},
+		ExpiryMinutes: 30,
+	}
+	groups := fakeUserGroups{"SLAUNCH": {"UBOB"}}
+
+	s := NewLaunchApprovals(policy, groups, clock)
+	request := s.Open("UREQUESTER", "C1")
+
+	steps := []struct {
+		name       string
+		user       string
+		channel    string
+		rocket     string
+		wantQuorum bool
+		wantErr    error
+	}{
+		{name: "The requester cannot approve", user: "UREQUESTER", rocket: "Falcon 9", wantErr: ErrRequesterCannotApprove},
+		{name: "Unknown users cannot approve", user: "UEVE", rocket: "Falcon 9", wantErr: ErrNotAnApprover},
+		{name: "Approvals come from the channel of the request", user: "UALICE", channel: "C2", rocket: "Falcon 9", wantErr: ErrWrongChannel},
+		{name: "Allowed users approve", user: "UALICE", rocket: "Falcon 9"},
+		{name: "Approvals are counted once", user: "UALICE", rocket: "Falcon 9", wantErr: ErrAlreadyApproved},
+		{name: "Another rocket resets the approvals", user: "UBOB", rocket: "Starship"},
+		{name: "The approvals are given again", user: "UALICE", rocket: "Starship", wantQuorum: true},
+		{name: "The request is closed with the quorum", user: "UBOB", rocket: "Starship", wantErr: ErrLaunchRequestNotFound},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			channel := step.channel
+			if channel == "" {
+				channel = request.Channel
+			}
+			_, quorum, err := s.Approve(request.ID, step.user, channel, step.rocket)
+			if err != step.wantErr {
+				t.Fatalf("Approve() error = %v, wantErr %v", err, step.wantErr)
+			}
+			if quorum != step.wantQuorum {
+				t.Errorf("Approve() quorum = %v, want %v", quorum, step.wantQuorum)
+			}
+		})
+	}
+}
+
+func TestLaunchApprovals_Open(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+
+	// The IDs do not repeat across restarts, an old button cannot match a new request
+	first := NewLaunchApprovals(DefaultApprovalPolicy(), nil, clock).Open("UREQUESTER", "C1")
+	second := NewLaunchApprovals(DefaultApprovalPolicy(), nil, clock).Open("UREQUESTER", "C1")
+	if first.ID == second.ID {
+		t.Errorf("Open() ID = %v for both requests, want distinct IDs", first.ID)
+	}
+}
+
+func TestLaunchApprovals_Expiry(t *testing.T) {
+	clock := NewFakeClock(time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC))
+
+	policy := ApprovalPolicy{Approvers: 2, AllowRequester: true, ExpiryMinutes: 30}
+	s := NewLaunchApprovals(policy, fakeUserGroups{}, clock)
+
+	request := s.Open("UREQUESTER", "C1")
+	got, _, err := s.Approve(request.ID, "UREQUESTER", "C1", "Falcon 9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(got.Approvers, []string{"UREQUESTER"}); diff != nil {
+		t.Error(diff)
+	}
+
+	// Stale requests cannot be approved anymore
+	clock.Advance(30 * time.Minute)
+	if _, _, err := s.Approve(request.ID, "UALICE", "C1", "Falcon 9"); err != ErrLaunchRequestExpired {
+		t.Errorf("Approve() error = %v, want %v", err, ErrLaunchRequestExpired)
+	}
+	if pending := s.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %v, want none", pending)
+	}
+}
+
+func TestLoadApprovalPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	ioutil.WriteFile(valid, []byte(`{"approvers": 2, "user_groups": ["SLAUNCH"]}`), 0644)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	ioutil.WriteFile(invalid, []byte(`{"approvers": 0}`), 0644)
+
+	tests := []struct {
+		name    string
+		file    string
+		want    ApprovalPolicy
+		wantErr bool
+	}{
+		{name: "No file", file: "", want: DefaultApprovalPolicy()},
+		{
+			name: "Defaults are kept",
+			file: valid,
+			want: ApprovalPolicy{Approvers: 2, AllowRequester: true, UserGroups: []string{"SLAUNCH"}, ExpiryMinutes: 60},
+		},
+		{name: "Invalid quorum", file: invalid, wantErr: true},
+		{name: "Missing file", file: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadApprovalPolicy(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadApprovalPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); !tt.wantErr && diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicy_Shared(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ApprovalPolicy
+		want   bool
+	}{
+		{name: "Requester approves", policy: DefaultApprovalPolicy(), want: false},
+		{name: "Quorum", policy: ApprovalPolicy{Approvers: 2, AllowRequester: true}, want: true},
+		{name: "Requester cannot approve", policy: ApprovalPolicy{Approvers: 1}, want: true},
+		{name: "Approvers", policy: ApprovalPolicy{Approvers: 1, AllowRequester: true, Users: []string{"U1"}}, want: true},
+		{name: "Approver groups", policy: ApprovalPolicy{Approvers: 1, AllowRequester: true, UserGroups: []string{"SLAUNCH"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Shared(); got != tt.want {
+				t.Errorf("Shared() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DecisionType is the answer given to a launch announcement
+type DecisionType string
+
+const (
+	LaunchApproved DecisionType = "approved"
+	LaunchDenied   DecisionType = "denied"
+	LaunchAborted  DecisionType = "aborted"
+)
+
+// LaunchDecision records who approved, denied or aborted a rocket launch and when
+type LaunchDecision struct {
+	ID        string
+	Type      DecisionType
+	Actor     string
+	Channel   string
+	Rocket    string
+	Reason    string
+	Timestamp time.Time
+}
+
+// DecisionLog keeps the history of the launch decisions
+type DecisionLog struct {
+	mu        sync.Mutex
+	decisions []LaunchDecision
+	now       func() time.Time
+}
+
+func NewDecisionLog() *DecisionLog {
+	return &DecisionLog{
+		now: time.Now,
+	}
+}
+
+// Record stores a decision and returns it with its ID and timestamp
+func (l *DecisionLog) Record(d LaunchDecision) LaunchDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d.ID = strconv.Itoa(len(l.decisions) + 1)
+	d.Timestamp = l.now()
+	l.decisions = append(l.decisions, d)
+
+	return d
+}
+
+// SetReason adds the reason given by the actor after the decision was recorded
+func (l *DecisionLog) SetReason(id string, reason string) (LaunchDecision, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.decisions {
+		if l.decisions[i].ID == id {
+			l.decisions[i].Reason = reason
+			return l.decisions[i], true
+		}
+	}
+
+	return LaunchDecision{}, false
+}
+
+// Decisions returns a copy of every decision, oldest first
+func (l *DecisionLog) Decisions() []LaunchDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]LaunchDecision{}, l.decisions...)
+}
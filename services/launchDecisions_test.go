@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestDecisionLog(t *testing.T) {
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	l := NewDecisionLog()
+	l.now = func() time.Time { return now }
+
+	approved := l.Record(LaunchDecision{Type: LaunchApproved, Actor: "U1", Channel: "C1", Rocket: "Falcon 9"})
+	denied := l.Record(LaunchDecision{Type: LaunchDenied, Actor: "U2", Channel: "C1", Rocket: "Starship"})
+
+	if approved.ID == denied.ID {
+		t.Errorf("Record() IDs should be unique, got %v twice", approved.ID)
+	}
+
+	// The reason is given later through a modal
+	if _, ok := l.SetReason(denied.ID, "Too windy"); !ok {
+		t.Errorf("SetReason() decision %v not found", denied.ID)
+	}
+	if _, ok := l.SetReason("unknown", "Too windy"); ok {
+		t.Errorf("SetReason() should fail for unknown decisions")
+	}
+
+	want := []LaunchDecision{
+		{ID: approved.ID, Type: LaunchApproved, Actor: "U1", Channel: "C1", Rocket: "Falcon 9", Timestamp: now},
+		{ID: denied.ID, Type: LaunchDenied, Actor: "U2", Channel: "C1", Rocket: "Starship", Reason: "Too windy", Timestamp: now},
+	}
+	if diff := deep.Equal(l.Decisions(), want); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LaunchOutcome is how a launch ended
+type LaunchOutcome string
+
+const (
+	OutcomeInFlight LaunchOutcome = "in_flight"
+	OutcomeLaunched LaunchOutcome = "launched"
+	OutcomeAborted  LaunchOutcome = "aborted"
+	OutcomeDenied   LaunchOutcome = "denied"
+)
+
+// LaunchRecord is an entry of the launch history
+type LaunchRecord struct {
+	ID        string
+	Requester string
+	Approvers []string
+	Rocket    string
+	Channel   string
+	Countdown int
+	Outcome   LaunchOutcome
+	Timestamp time.Time
+	// Silent launches were only seen by their requester, they stay out of the stats
+	Silent bool
+}
+
+// LaunchFilter keeps the launches a user is allowed to see
+type LaunchFilter func(r LaunchRecord) bool
+
+// LeaderboardEntry counts the successful launches of a user or a channel
+type LeaderboardEntry struct {
+	ID       string
+	Launches int
+}
+
+// LaunchHistory keeps every rocket launch
+type LaunchHistory struct {
+	mu      sync.Mutex
+	records []LaunchRecord
+	now     func() time.Time
+}
+
+func NewLaunchHistory() *LaunchHistory {
+	return &LaunchHistory{
+		now: time.Now,
+	}
+}
+
+// Record stores a launch and returns it with its ID and timestamp
+func (h *LaunchHistory) Record(r LaunchRecord) LaunchRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r.ID = strconv.Itoa(len(h.records) + 1)
+	r.Timestamp = h.now()
+	h.records = append(h.records, r)
+
+	return r
+}
+
+// SetOutcome updates a launch once it is over
+func (h *LaunchHistory) SetOutcome(id string, outcome LaunchOutcome) (LaunchRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range h.records {
+		if h.records[i].ID == id {
+			h.records[i].Outcome = outcome
+			return h.records[i], true
+		}
+	}
+
+	return LaunchRecord{}, false
+}
+
+// Recent returns the last launches, newest first
+func (h *LaunchHistory) Recent(limit int) []LaunchRecord {
+	return h.RecentMatching(limit, nil)
+}
+
+// RecentMatching returns the last launches kept by filter, newest first
+// the filter runs without the lock of the history, it can call the slack API
+func (h *LaunchHistory) RecentMatching(limit int, filter LaunchFilter) []LaunchRecord {
+	records := h.snapshot()
+
+	recent := []LaunchRecord{}
+	for i := len(records) - 1; i >= 0 && len(recent) < limit; i-- {
+		if filter == nil || filter(records[i]) {
+			recent = append(recent, records[i])
+		}
+	}
+
+	return recent
+}
+
+// Leaderboards ranks the requesters and the channels by successful launches
+func (h *LaunchHistory) Leaderboards(limit int) ([]LeaderboardEntry, []LeaderboardEntry) {
+	return h.LeaderboardsMatching(limit, nil)
+}
+
+// LeaderboardsMatching ranks the requesters and the channels by the successful launches kept by filter
+func (h *LaunchHistory) LeaderboardsMatching(limit int, filter LaunchFilter) ([]LeaderboardEntry, []LeaderboardEntry) {
+	users := map[string]int{}
+	channels := map[string]int{}
+	for _, r := range h.snapshot() {
+		if r.Outcome != OutcomeLaunched {
+			continue
+		}
+		if filter != nil && !filter(r) {
+			continue
+		}
+		users[r.Requester]++
+		channels[r.Channel]++
+	}
+
+	return leaderboard(users, limit), leaderboard(channels, limit)
+}
+
+// snapshot copies the records so they can be read without the lock
+func (h *LaunchHistory) snapshot() []LaunchRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]LaunchRecord{}, h.records...)
+}
+
+// leaderboard sorts by launches then by ID so the ranking is stable
+func leaderboard(counts map[string]int, limit int) []LeaderboardEntry {
+	entries := []LeaderboardEntry{}
+	for id, launches := range counts {
+		entries = append(entries, LeaderboardEntry{ID: id, Launches: launches})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Launches != entries[j].Launches {
+			return entries[i].Launches > entries[j].Launches
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestLaunchHistory(t *testing.T) {
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	h := NewLaunchHistory()
+	h.now = func() time.Time { return now }
+
+	h.Record(LaunchRecord{Requester: "U1", Channel: "C1", Rocket: "Falcon 9", Outcome: OutcomeLaunched})
+	h.Record(LaunchRecord{Requester: "U2", Channel: "C1", Rocket: "Starship", Outcome: OutcomeDenied})
+	h.Record(LaunchRecord{Requester: "U2", Channel: "C2", Rocket: "Starship", Outcome: OutcomeLaunched})
+	inFlight := h.Record(LaunchRecord{Requester: "U2", Channel: "C2", Rocket: "Starship", Approvers: []string{"U1"}, Countdown: 10, Outcome: OutcomeInFlight})
+
+	// The count down is over
+	if _, ok := h.SetOutcome(inFlight.ID, OutcomeLaunched); !ok {
+		t.Errorf("SetOutcome() launch %v not found", inFlight.ID)
+	}
+
+	recent := h.Recent(2)
+	want := []LaunchRecord{
+		{ID: "4", Requester: "U2", Channel: "C2", Rocket: "Starship", Approvers: []string{"U1"}, Countdown: 10, Outcome: OutcomeLaunched, Timestamp: now},
+		{ID: "3", Requester: "U2", Channel: "C2", Rocket: "Starship", Outcome: OutcomeLaunched, Timestamp: now},
+	}
+	if diff := deep.Equal(recent, want); diff != nil {
+		t.Error(diff)
+	}
+
+	// Only successful launches count
+	users, channels := h.Leaderboards(10)
+	if diff := deep.Equal(users, []LeaderboardEntry{{ID: "U2", Launches: 2}, {ID: "U1", Launches: 1}}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(channels, []LeaderboardEntry{{ID: "C2", Launches: 2}, {ID: "C1", Launches: 1}}); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	ErrScheduledLaunchNotFound = errors.New("no pending launch with this ID")
+	ErrNotTheRequester         = errors.New("only the requester can cancel this launch")
+)
+
+// ScheduledLaunch is a launch announcement waiting for its time
+// Launch is the launch parameters, kept as is for the controller
+type ScheduledLaunch struct {
+	ID        string
+	Requester string
+	Channel   string
+	At        time.Time
+	Launch    string
+}
+
+// LaunchScheduler posts launch announcements at a later time
+// the announcement is posted by the App itself, so it can open
+// the launch request for approvals only when the time has come
+type LaunchScheduler struct {
+	clock Clock
+
+	mu       sync.Mutex
+	seq      int
+	launches map[string]ScheduledLaunch
+	timers   map[string]Timer
+}
+
+func NewLaunchScheduler(clock Clock) *LaunchScheduler {
+	return &LaunchScheduler{
+		clock:    clock,
+		launches: make(map[string]ScheduledLaunch),
+		timers:   make(map[string]Timer),
+	}
+}
+
+// Now is the time of the scheduler clock
+func (s *LaunchScheduler) Now() time.Time {
+	return s.clock.Now()
+}
+
+// Schedule calls fire with the launch at the given time
+func (s *LaunchScheduler) Schedule(launch ScheduledLaunch, fire func(ScheduledLaunch)) ScheduledLaunch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	launch.ID = strconv.Itoa(s.seq)
+	s.launches[launch.ID] = launch
+
+	s.timers[launch.ID] = s.clock.AfterFunc(launch.At.Sub(s.clock.Now()), func() {
+		s.mu.Lock()
+		_, ok := s.launches[launch.ID]
+		delete(s.launches, launch.ID)
+		delete(s.timers, launch.ID)
+		s.mu.Unlock()
+
+		// The launch may have been cancelled at the last moment
+		if ok {
+			fire(launch)
+		}
+	})
+
+	return launch
+}
+
+// Pending lists the launches of a channel that are not announced yet, soonest first
+func (s *LaunchScheduler) Pending(channel string) []ScheduledLaunch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	launches := []ScheduledLaunch{}
+	for _, launch := range s.launches {
+		if launch.Channel == channel {
+			launches = append(launches, launch)
+		}
+	}
+
+	sort.Slice(launches, func(i, j int) bool {
+		return launches[i].At.Before(launches[j].At)
+	})
+
+	return launches
+}
+
+// Cancel removes a pending launch, only its requester can cancel it
+func (s *LaunchScheduler) Cancel(id string, user string) (ScheduledLaunch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	launch, ok := s.launches[id]
+	if !ok {
+		return launch, ErrScheduledLaunchNotFound
+	}
+
+	if launch.Requester != user {
+		return launch, ErrNotTheRequester
+	}
+
+	s.timers[id].Stop()
+	delete(s.timers, id)
+	delete(s.launches, id)
+
+	return launch, nil
+}
+
+// UserLocation is the timezone of a slack user
+// the offset is used when the timezone name is unknown to the system
+func UserLocation(user *slack.User) *time.Location {
+	if user == nil {
+		return time.UTC
+	}
+
+	if loc, err := time.LoadLocation(user.TZ); err == nil && user.TZ != "" {
+		return loc
+	}
+
+	return time.FixedZone(user.TZLabel, user.TZOffset)
+}
+
+// NextWallClock is the next time the clock shows hh:mm in the given location
+// e.g. 15:30 is today if it is 10:00, tomorrow if it is 16:00
+func NextWallClock(now time.Time, clock string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a time (e.g. 15:30)", clock)
+	}
+
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestLaunchScheduler(t *testing.T) {
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	s := NewLaunchScheduler(clock)
+
+	fired := []string{}
+	fire := func(launch ScheduledLaunch) {
+		fired = append(fired, launch.ID)
+	}
+
+	later := s.Schedule(ScheduledLaunch{Requester: "U1", Channel: "C1", At: now.Add(2 * time.Hour)}, fire)
+	soon := s.Schedule(ScheduledLaunch{Requester: "U1", Channel: "C1", At: now.Add(time.Hour)}, fire)
+	cancelled := s.Schedule(ScheduledLaunch{Requester: "U2", Channel: "C1", At: now.Add(time.Hour)}, fire)
+
+	// Soonest first
+	pending := s.Pending("C1")
+	if len(pending) != 3 || pending[2].ID != later.ID {
+		t.Errorf("Pending() = %+v", pending)
+	}
+
+	if _, err := s.Cancel(cancelled.ID, "U1"); err != ErrNotTheRequester {
+		t.Errorf("Cancel() error = %v, want %v", err, ErrNotTheRequester)
+	}
+	if _, err := s.Cancel(cancelled.ID, "U2"); err != nil {
+		t.Errorf("Cancel() error = %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	if len(fired) != 1 || fired[0] != soon.ID {
+		t.Errorf("fired = %v, want [%v]", fired, soon.ID)
+	}
+
+	clock.Advance(time.Hour)
+	if len(fired) != 2 || fired[1] != later.ID {
+		t.Errorf("fired = %v, want [%v %v]", fired, soon.ID, later.ID)
+	}
+
+	if pending := s.Pending("C1"); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want none", pending)
+	}
+	if _, err := s.Cancel(later.ID, "U1"); err != ErrScheduledLaunchNotFound {
+		t.Errorf("Cancel() error = %v, want %v", err, ErrScheduledLaunchNotFound)
+	}
+}
+
+func TestNextWallClock(t *testing.T) {
+	paris := UserLocation(&slack.User{TZ: "Europe/Paris"})
+
+	// 12:00 UTC is 14:00 in Paris
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		clock   string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "Later today", clock: "15:30", loc: paris, want: time.Date(2021, 5, 8, 13, 30, 0, 0, time.UTC)},
+		{name: "Tomorrow", clock: "13:00", loc: paris, want: time.Date(2021, 5, 9, 11, 0, 0, 0, time.UTC)},
+		{name: "Offset only", clock: "13:00", loc: UserLocation(&slack.User{TZ: "Unknown/Zone", TZOffset: -3600}), want: time.Date(2021, 5, 8, 14, 0, 0, 0, time.UTC)},
+		{name: "Invalid time", clock: "soon", loc: time.UTC, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextWallClock(now, tt.clock, tt.loc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextWallClock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("NextWallClock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
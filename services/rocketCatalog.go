@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// MaxCountDown keeps the count down of a launch reasonable
+const MaxCountDown = 60
+
+// Rocket is an entry of the catalog
+// Frames are the images of the count down, the last one is shown
+// while the count down is longer than the number of frames
+type Rocket struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Frames      []string `json:"frames"`
+	Countdown   int      `json:"countdown"`
+}
+
+// Frame is the image shown when remaining seconds are left
+func (r Rocket) Frame(remaining int) string {
+	if remaining >= len(r.Frames) {
+		remaining = len(r.Frames) - 1
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return r.Frames[remaining]
+}
+
+// RocketCatalog is the list of rockets /rocket can launch
+// Default is the name of the rocket launched when none is picked
+type RocketCatalog struct {
+	Rockets []Rocket `json:"rockets"`
+	Default string   `json:"default"`
+}
+
+// defaultFrames are the count down images embedded in the App
+var defaultFrames = []string{
+	AssetScheme + "rocket0.png",
+	AssetScheme + "rocket1.png",
+	AssetScheme + "rocket2.png",
+	AssetScheme + "rocket3.png",
+}
+
+// DefaultRocketCatalog only contains the Falcon 9
+func DefaultRocketCatalog() RocketCatalog {
+	return RocketCatalog{
+		Rockets: []Rocket{
+			{
+				Name:        "Falcon 9",
+				Description: "Reusable two-stage rocket",
+				Frames:      defaultFrames,
+				Countdown:   3,
+			},
+		},
+		Default: "Falcon 9",
+	}
+}
+
+// LoadRocketCatalog reads and validates the rocket catalog file
+// the asset:// frames must be images of assets, the ones embedded in the App
+// When no file is provided we use the DefaultRocketCatalog
+func LoadRocketCatalog(file string, assets fs.FS) (RocketCatalog, error) {
+	if file == "" {
+		return DefaultRocketCatalog(), nil
+	}
+
+	catalog := RocketCatalog{}
+
+	str, err := ioutil.ReadFile(file)
+	if err != nil {
+		return catalog, err
+	}
+
+	if err := json.Unmarshal(str, &catalog); err != nil {
+		return catalog, err
+	}
+
+	return catalog, catalog.Validate(assets)
+}
+
+// Validate checks the catalog can be used by /rocket
+// and that its asset:// frames are found in assets
+func (c RocketCatalog) Validate(assets fs.FS) error {
+	if len(c.Rockets) == 0 {
+		return fmt.Errorf("the catalog has no rocket")
+	}
+
+	names := map[string]bool{}
+	for _, rocket := range c.Rockets {
+		if rocket.Name == "" {
+			return fmt.Errorf("a rocket has no name")
+		}
+
+		key := strings.ToLower(rocket.Name)
+		if names[key] {
+			return fmt.Errorf("rocket %q is defined twice", rocket.Name)
+		}
+		names[key] = true
+
+		if len(rocket.Frames) == 0 {
+			return fmt.Errorf("rocket %q has no frame", rocket.Name)
+		}
+		for _, frame := range rocket.Frames {
+			if u, err := url.Parse(frame); err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "asset") {
+				return fmt.Errorf("rocket %q has an invalid frame %q", rocket.Name, frame)
+			}
+			if !strings.HasPrefix(frame, AssetScheme) {
+				continue
+			}
+			if info, err := fs.Stat(assets, strings.TrimPrefix(frame, AssetScheme)); err != nil || info.IsDir() {
+				return fmt.Errorf("rocket %q has an unknown image %q", rocket.Name, frame)
+			}
+		}
+
+		if rocket.Countdown < 0 || rocket.Countdown > MaxCountDown {
+			return fmt.Errorf("rocket %q count down must be between 0 and %d seconds", rocket.Name, MaxCountDown)
+		}
+	}
+
+	if _, ok := c.Find(c.Default); !ok {
+		return fmt.Errorf("unknown default rocket %q", c.Default)
+	}
+
+	return nil
+}
+
+// Assets lists the embedded images used as frames, e.g. rocket0.png for asset://rocket0.png
+func (c RocketCatalog) Assets() []string {
+	assets := []string{}
+	for _, rocket := range c.Rockets {
+		for _, frame := range rocket.Frames {
+			if strings.HasPrefix(frame, AssetScheme) {
+				assets = append(assets, strings.TrimPrefix(frame, AssetScheme))
+			}
+		}
+	}
+
+	return assets
+}
+
+// Find looks a rocket up by name, ignoring the case
+func (c RocketCatalog) Find(name string) (Rocket, bool) {
+	for _, rocket := range c.Rockets {
+		if strings.EqualFold(rocket.Name, name) {
+			return rocket, true
+		}
+	}
+
+	return Rocket{}, false
+}
+
+// DefaultRocket is launched when none is picked
+func (c RocketCatalog) DefaultRocket() Rocket {
+	rocket, _ := c.Find(c.Default)
+	return rocket
+}
+
+// Search lists the rockets whose name contains the query, ignoring the case
+func (c RocketCatalog) Search(query string) []Rocket {
+	query = strings.ToLower(query)
+
+	rockets := []Rocket{}
+	for _, rocket := range c.Rockets {
+		if strings.Contains(strings.ToLower(rocket.Name), query) {
+			rockets = append(rockets, rocket)
+		}
+	}
+
+	return rockets
+}
+
+// Names lists the names of the rockets
+func (c RocketCatalog) Names() []string {
+	names := []string{}
+	for _, rocket := range c.Rockets {
+		names = append(names, rocket.Name)
+	}
+
+	return names
+}
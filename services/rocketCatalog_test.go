@@ -0,0 +1,150 @@
+package services
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-test/deep"
+)
+
+// testAssets are the images embedded in the App
+var testAssets = fstest.MapFS{
+	"rocket0.png": {Data: []byte("rocket0")},
+	"rocket1.png": {Data: []byte("rocket1")},
+}
+
+func TestLoadRocketCatalog(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		ioutil.WriteFile(file, []byte(content), 0644)
+		return file
+	}
+
+	valid := write("valid.json", `{
+		"default": "Starship",
+		"rockets": [
+			{"name": "Starship", "description": "Fully reusable", "frames": ["https://example.com/0.png"], "countdown": 10}
+		]
+	}`)
+
+	tests := []struct {
+		name    string
+		file    string
+		want    RocketCatalog
+		wantErr bool
+	}{
+		{name: "No file", file: "", want: DefaultRocketCatalog()},
+		{
+			name: "Valid catalog",
+			file: valid,
+			want: RocketCatalog{
+				Default: "Starship",
+				Rockets: []Rocket{
+					{Name: "Starship", Description: "Fully reusable", Frames: []string{"https://example.com/0.png"}, Countdown: 10},
+				},
+			},
+		},
+		{
+			name:    "Unknown default",
+			file:    write("default.json", `{"default": "Saturn V", "rockets": [{"name": "Starship", "frames": ["https://example.com/0.png"]}]}`),
+			wantErr: true,
+		},
+		{
+			name:    "Duplicated rocket",
+			file:    write("twice.json", `{"default": "a", "rockets": [{"name": "a", "frames": ["https://example.com/0.png"]}, {"name": "A", "frames": ["https://example.com/0.png"]}]}`),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid frame",
+			file:    write("frame.json", `{"default": "a", "rockets": [{"name": "a", "frames": ["rocket0.png"]}]}`),
+			wantErr: true,
+		},
+		{
+			name: "Embedded frame",
+			file: write("asset.json", `{"default": "a", "rockets": [{"name": "a", "frames": ["asset://rocket0.png"]}]}`),
+			want: RocketCatalog{Default: "a", Rockets: []Rocket{{Name: "a", Frames: []string{"asset://rocket0.png"}}}},
+		},
+		{
+			name:    "Unknown embedded frame",
+			file:    write("missing.json", `{"default": "a", "rockets": [{"name": "a", "frames": ["asset://rocket9.png"]}]}`),
+			wantErr: true,
+		},
+		{
+			name:    "Count down too long",
+			file:    write("count.json", `{"default": "a", "rockets": [{"name": "a", "frames": ["https://example.com/0.png"], "countdown": 3600}]}`),
+			wantErr: true,
+		},
+		{
+			name:    "Empty catalog",
+			file:    write("empty.json", `{}`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRocketCatalog(tt.file, testAssets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRocketCatalog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); !tt.wantErr && diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRocket_Frame(t *testing.T) {
+	rocket := Rocket{Frames: []string{"0.png", "1.png", "2.png"}}
+
+	tests := []struct {
+		remaining int
+		want      string
+	}{
+		{remaining: 0, want: "0.png"},
+		{remaining: 2, want: "2.png"},
+		// Long count downs show the last frame until the frames are available
+		{remaining: 10, want: "2.png"},
+	}
+	for _, tt := range tests {
+		if got := rocket.Frame(tt.remaining); got != tt.want {
+			t.Errorf("Frame(%v) = %v, want %v", tt.remaining, got, tt.want)
+		}
+	}
+}
+
+func TestRocketCatalog_Search(t *testing.T) {
+	catalog := RocketCatalog{
+		Rockets: []Rocket{{Name: "Falcon 9"}, {Name: "Falcon Heavy"}, {Name: "Starship"}},
+	}
+
+	got := []string{}
+	for _, rocket := range catalog.Search("falcon") {
+		got = append(got, rocket.Name)
+	}
+
+	if diff := deep.Equal(got, []string{"Falcon 9", "Falcon Heavy"}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestRocketCatalog_Assets(t *testing.T) {
+	catalog := RocketCatalog{
+		Rockets: []Rocket{
+			{Name: "Falcon 9", Frames: []string{"asset://rocket0.png", "https://example.com/1.png"}},
+			{Name: "Starship", Frames: []string{"asset://rocket1.png"}},
+		},
+		Default: "Falcon 9",
+	}
+
+	if err := catalog.Validate(testAssets); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	if diff := deep.Equal(catalog.Assets(), []string{"rocket0.png", "rocket1.png"}); diff != nil {
+		t.Error(diff)
+	}
+}
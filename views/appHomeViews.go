@@ -7,18 +7,17 @@ import (
 	"io/ioutil"
 	"log"
 
-	"encoding/json"
-
 	"github.com/slack-go/slack"
 )
 
 const (
 	// Define Action_id as constant so we can refet to them in the controller
-	AddStockieNoteActionID   = "add_note"
-	ModalDescriptionBlockID  = "note_description"
-	ModalDescriptionActionID = "content"
-	ModalColorBlockID        = "note_color"
-	ModalColorActionID       = "color"
+	AddStockieNoteActionID      = "add_note"
+	CreateStickieNoteCallbackID = "create_stickie_note"
+	ModalDescriptionBlockID     = "note_description"
+	ModalDescriptionActionID    = "content"
+	ModalColorBlockID           = "note_color"
+	ModalColorActionID          = "color"
 )
 
 type StickieNote struct {
@@ -37,7 +36,7 @@ func AppHomeTabView() slack.HomeTabViewRequest {
 		log.Printf("Unable to read view `AppHomeView`: %v", err)
 	}
 	view := slack.HomeTabViewRequest{}
-	json.Unmarshal([]byte(str), &view)
+	unmarshalView("appHomeViewsAssets/AppHomeView.json", str, &view)
 
 	return view
 }
@@ -49,7 +48,7 @@ func CreateStickieNoteModal() slack.ModalViewRequest {
 		log.Printf("Unable to read view `CreateStickieNoteModal`: %v", err)
 	}
 	view := slack.ModalViewRequest{}
-	json.Unmarshal([]byte(str), &view)
+	unmarshalView("appHomeViewsAssets/CreateStickieNoteModal.json", str, &view)
 
 	return view
 }
@@ -62,7 +61,7 @@ func AppHomeCreateStickieNote(note StickieNote) slack.HomeTabViewRequest {
 		log.Printf("Unable to read view `AppHomeView`: %v", err)
 	}
 	view := slack.HomeTabViewRequest{}
-	json.Unmarshal(str, &view)
+	unmarshalView("appHomeViewsAssets/AppHomeView.json", str, &view)
 
 	// New Notes
 	t, err := template.ParseFS(appHomeAssets, "appHomeViewsAssets/NoteBlock.json")
@@ -76,9 +75,57 @@ func AppHomeCreateStickieNote(note StickieNote) slack.HomeTabViewRequest {
 	}
 	str, _ = ioutil.ReadAll(&tpl)
 	note_view := slack.HomeTabViewRequest{}
-	json.Unmarshal(str, &note_view)
+	unmarshalView("appHomeViewsAssets/NoteBlock.json", str, &note_view)
 
 	view.Blocks.BlockSet = append(view.Blocks.BlockSet, note_view.Blocks.BlockSet...)
 
 	return view
 }
+
+// Mentee is a new member followed by the user as a buddy
+type Mentee struct {
+	User    string
+	Channel string
+	Since   string
+}
+
+func AppHomeMenteesBlocks(mentees []Mentee) []slack.Block {
+
+	// Nothing to display, the section is hidden
+	if len(mentees) == 0 {
+		return nil
+	}
+
+	// Section header
+	str, err := appHomeAssets.ReadFile("appHomeViewsAssets/MenteesBlock.json")
+	if err != nil {
+		log.Printf("Unable to read view `MenteesBlock`: %v", err)
+	}
+	view := slack.HomeTabViewRequest{}
+	unmarshalView("appHomeViewsAssets/MenteesBlock.json", str, &view)
+
+	// One entry per mentee
+	type args struct {
+		User    template.HTML
+		Channel template.HTML
+		Since   string
+	}
+
+	for _, mentee := range mentees {
+		my_args := args{
+			User:    userMention(mentee.User),
+			Channel: channelMention(mentee.Channel),
+			Since:   mentee.Since,
+		}
+
+		tpl := renderTemplate(appHomeAssets, "appHomeViewsAssets/MenteeBlock.json", my_args)
+
+		str, _ = ioutil.ReadAll(&tpl)
+		mentee_view := slack.HomeTabViewRequest{}
+		unmarshalView("appHomeViewsAssets/MenteeBlock.json", str, &mentee_view)
+
+		view.Blocks.BlockSet = append(view.Blocks.BlockSet, mentee_view.Blocks.BlockSet...)
+	}
+
+	return view.Blocks.BlockSet
+}
@@ -67,7 +67,8 @@ func TestCreateStickieNoteModal(t *testing.T) {
 		{
 			name: "Simple Modal",
 			want: slack.ModalViewRequest{
-				Type: slack.VTModal,
+				Type:       slack.VTModal,
+				CallbackID: CreateStickieNoteCallbackID,
 				Title: &slack.TextBlockObject{
 					Type:     "plain_text",
 					Text:     "Create a stickie note",
@@ -210,3 +211,57 @@ func TestAppHomeCreateStickieNote(t *testing.T) {
 		})
 	}
 }
+
+func TestAppHomeMenteesBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		mentees []Mentee
+		want    []slack.Block
+	}{
+		{
+			name:    "No mentees",
+			mentees: []Mentee{},
+			want:    nil,
+		},
+		{
+			name: "1 Mentee",
+			mentees: []Mentee{
+				{User: "U0123456", Channel: "C0123456", Since: "Today"},
+			},
+			want: []slack.Block{
+				&slack.HeaderBlock{
+					Type: slack.MBTHeader,
+					Text: &slack.TextBlockObject{
+						Type: "plain_text",
+						Text: "Your mentees",
+					},
+				},
+				&slack.SectionBlock{
+					Type: slack.MBTSection,
+					Text: &slack.TextBlockObject{
+						Type: "mrkdwn",
+						Text: "<@U0123456> joined <#C0123456>",
+					},
+				},
+				&slack.ContextBlock{
+					Type: slack.MBTContext,
+					ContextElements: slack.ContextElements{
+						Elements: []slack.MixedElement{
+							&slack.TextBlockObject{
+								Type: "mrkdwn",
+								Text: "Your buddy since Today",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := deep.Equal(AppHomeMenteesBlocks(tt.mentees), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package views
+
+import (
+	"embed"
+	"io/fs"
+	"strings"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+)
+
+//go:embed slackCommandAssets/*.png
+var imageAssets embed.FS
+
+// AssetResolver gives the slack file of an embedded image once it is uploaded
+type AssetResolver interface {
+	FileID(name string) (string, bool)
+}
+
+// assets is set once at startup by UseAssets
+var assets AssetResolver
+
+// UseAssets makes the views reference the uploaded images
+func UseAssets(resolver AssetResolver) {
+	assets = resolver
+}
+
+// assetBaseURL is set once at startup by UseAssetBaseURL
+var assetBaseURL string
+
+// UseAssetBaseURL serves the images that are not uploaded yet from a public copy of the assets
+// e.g. https://example.com/assets/ for https://example.com/assets/rocket0.png
+func UseAssetBaseURL(baseURL string) {
+	assetBaseURL = baseURL
+}
+
+// ImageAssets are the embedded images, by name, to upload to slack
+func ImageAssets() fs.FS {
+	images, _ := fs.Sub(imageAssets, "slackCommandAssets")
+	return images
+}
+
+// HasImageAsset checks that an image is embedded in the App
+func HasImageAsset(name string) bool {
+	_, err := fs.Stat(ImageAssets(), name)
+	return err == nil
+}
+
+// slackFile references an uploaded file in a block
+type slackFile struct {
+	ID string `json:"id"`
+}
+
+// slackFileImageBlock is an image block showing an uploaded file
+// slack.ImageBlock only knows about image_url
+type slackFileImageBlock struct {
+	Type      slack.MessageBlockType `json:"type"`
+	SlackFile slackFile              `json:"slack_file"`
+	AltText   string                 `json:"alt_text"`
+	BlockID   string                 `json:"block_id,omitempty"`
+	Title     *slack.TextBlockObject `json:"title,omitempty"`
+}
+
+// BlockType returns the type of the block
+func (b slackFileImageBlock) BlockType() slack.MessageBlockType {
+	return b.Type
+}
+
+// slackFileImageElement is an image element, in a context or as an accessory, showing an uploaded file
+type slackFileImageElement struct {
+	Type      slack.MessageElementType `json:"type"`
+	SlackFile slackFile                `json:"slack_file"`
+	AltText   string                   `json:"alt_text"`
+}
+
+// ElementType returns the type of the element
+func (e slackFileImageElement) ElementType() slack.MessageElementType {
+	return e.Type
+}
+
+// MixedElementType lets the element be used in a context block
+func (e slackFileImageElement) MixedElementType() slack.MixedElementType {
+	return slack.MixedElementImage
+}
+
+// slackFileSectionBlock is a section block whose accessory is an uploaded file
+// slack.Accessory cannot hold another element type
+type slackFileSectionBlock struct {
+	*slack.SectionBlock
+	Accessory *slackFileImageElement `json:"accessory"`
+}
+
+// assetImage tells how to show an image URL, either by the ID of the uploaded file or by a URL
+// both are empty for an embedded image that is not uploaded yet and has no public copy
+func assetImage(imageURL string) (fileID string, url string) {
+	if !strings.HasPrefix(imageURL, services.AssetScheme) {
+		return "", imageURL
+	}
+
+	name := strings.TrimPrefix(imageURL, services.AssetScheme)
+
+	if assets != nil {
+		if id, ok := assets.FileID(name); ok {
+			return id, ""
+		}
+	}
+
+	if assetBaseURL != "" {
+		return "", assetBaseURL + name
+	}
+
+	return "", ""
+}
+
+// resolveAssets replaces the images referenced with services.AssetScheme by their slack file,
+// in image blocks, section accessories and context elements
+// Until it is uploaded an image is served from the base URL, or shown as its alt text without one
+func resolveAssets(blocks []slack.Block) []slack.Block {
+	for i, block := range blocks {
+		switch block := block.(type) {
+		case *slack.ImageBlock:
+			blocks[i] = resolveImageBlock(block)
+		case *slack.SectionBlock:
+			blocks[i] = resolveAccessory(block)
+		case *slack.ContextBlock:
+			for j, element := range block.ContextElements.Elements {
+				if image, ok := element.(*slack.ImageBlockElement); ok {
+					block.ContextElements.Elements[j] = resolveImageElement(image)
+				}
+			}
+		}
+	}
+
+	return blocks
+}
+
+func resolveImageBlock(image *slack.ImageBlock) slack.Block {
+	id, url := assetImage(image.ImageURL)
+	switch {
+	case id != "":
+		return &slackFileImageBlock{
+			Type:      slack.MBTImage,
+			SlackFile: slackFile{ID: id},
+			AltText:   image.AltText,
+			BlockID:   image.BlockID,
+			Title:     image.Title,
+		}
+	case url != "":
+		image.ImageURL = url
+		return image
+	}
+
+	return slack.NewContextBlock(image.BlockID, slack.NewTextBlockObject(slack.PlainTextType, image.AltText, false, false))
+}
+
+func resolveAccessory(section *slack.SectionBlock) slack.Block {
+	if section.Accessory == nil || section.Accessory.ImageElement == nil {
+		return section
+	}
+	image := section.Accessory.ImageElement
+
+	id, url := assetImage(image.ImageURL)
+	switch {
+	case id != "":
+		return &slackFileSectionBlock{
+			SectionBlock: section,
+			Accessory:    &slackFileImageElement{Type: slack.METImage, SlackFile: slackFile{ID: id}, AltText: image.AltText},
+		}
+	case url != "":
+		image.ImageURL = url
+		return section
+	}
+
+	// The text of the section is enough without the image
+	section.Accessory = nil
+	return section
+}
+
+func resolveImageElement(image *slack.ImageBlockElement) slack.MixedElement {
+	id, url := assetImage(image.ImageURL)
+	switch {
+	case id != "":
+		return &slackFileImageElement{Type: slack.METImage, SlackFile: slackFile{ID: id}, AltText: image.AltText}
+	case url != "":
+		image.ImageURL = url
+		return image
+	}
+
+	return slack.NewTextBlockObject(slack.PlainTextType, image.AltText, false, false)
+}
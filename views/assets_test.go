@@ -0,0 +1,91 @@
+package views
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"xnok/slack-go-demo/services"
+
+	"github.com/slack-go/slack"
+)
+
+type fakeAssets map[string]string
+
+func (f fakeAssets) FileID(name string) (string, bool) {
+	id, ok := f[name]
+	return id, ok
+}
+
+func TestHasImageAsset(t *testing.T) {
+	if !HasImageAsset("rocket0.png") {
+		t.Error("HasImageAsset(rocket0.png) = false")
+	}
+	if HasImageAsset("rocket.json") {
+		t.Error("HasImageAsset(rocket.json) = true")
+	}
+}
+
+func TestLaunchRocket_Assets(t *testing.T) {
+	defer UseAssets(nil)
+	defer UseAssetBaseURL("")
+
+	// Until it is uploaded the image is shown as its alt text
+	UseAssets(fakeAssets{})
+
+	blocks := LaunchRocket(3, services.AssetScheme+"rocket0.png", RocketLaunch{})
+	str, _ := json.Marshal(blocks[0])
+	if !strings.Contains(string(str), `"text":"3"`) || strings.Contains(string(str), "image_url") {
+		t.Errorf("block = %s", str)
+	}
+
+	// or served from the public copy of the assets
+	UseAssetBaseURL("https://example.com/assets/")
+
+	blocks = LaunchRocket(3, services.AssetScheme+"rocket0.png", RocketLaunch{})
+	if image := blocks[0].(*slack.ImageBlock).ImageURL; image != "https://example.com/assets/rocket0.png" {
+		t.Errorf("ImageURL = %v", image)
+	}
+
+	// Then the block references the slack file
+	UseAssets(fakeAssets{"rocket0.png": "F0123456"})
+
+	blocks = LaunchRocket(3, services.AssetScheme+"rocket0.png", RocketLaunch{})
+	str, _ = json.Marshal(blocks[0])
+	if !strings.Contains(string(str), `"slack_file":{"id":"F0123456"}`) || strings.Contains(string(str), "image_url") {
+		t.Errorf("block = %s", str)
+	}
+}
+
+func TestUnmarshalView_Assets(t *testing.T) {
+	defer UseAssets(nil)
+
+	UseAssets(fakeAssets{"rocket0.png": "F0123456"})
+
+	str := []byte(`{"blocks":[
+		{"type":"section","text":{"type":"mrkdwn","text":"Ready"},"accessory":{"type":"image","image_url":"asset://rocket0.png","alt_text":"rocket"}},
+		{"type":"context","elements":[{"type":"image","image_url":"asset://rocket0.png","alt_text":"rocket"},{"type":"image","image_url":"asset://rocket1.png","alt_text":"later"}]},
+		{"type":"section","text":{"type":"mrkdwn","text":"Public"},"accessory":{"type":"image","image_url":"https://example.com/note.png","alt_text":"note"}}
+	]}`)
+
+	view := slack.Msg{}
+	unmarshalView("test.json", str, &view)
+
+	got, _ := json.Marshal(view.Blocks)
+
+	want := []string{
+		// the accessory of the section
+		`"text":{"type":"mrkdwn","text":"Ready"},"accessory":{"type":"image","slack_file":{"id":"F0123456"},"alt_text":"rocket"}`,
+		// the elements of the context, rocket1.png is not uploaded
+		`"elements":[{"type":"image","slack_file":{"id":"F0123456"},"alt_text":"rocket"},{"type":"plain_text","text":"later"}]`,
+		// the other images are kept
+		`"image_url":"https://example.com/note.png"`,
+	}
+	for _, w := range want {
+		if !strings.Contains(string(got), w) {
+			t.Errorf("%s does not contain %s", got, w)
+		}
+	}
+	if strings.Contains(string(got), services.AssetScheme) {
+		t.Errorf("%s still references the assets", got)
+	}
+}
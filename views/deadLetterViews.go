@@ -0,0 +1,155 @@
+package views
+
+import (
+	"embed"
+	"html/template"
+	"io/ioutil"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+//go:embed deadLetterViewsAssets/*
+var deadLetterAssets embed.FS
+
+// maxEnvelopeLength keeps the envelope under the 3000 characters of a section
+const maxEnvelopeLength = 2900
+
+// DeadLetter is an event whose handler failed
+type DeadLetter struct {
+	ID       string
+	Time     time.Time
+	Event    string
+	Handler  string
+	Error    string
+	Attempts int
+}
+
+// deadLetterArgs are the template arguments of a dead letter
+type deadLetterArgs struct {
+	ID       string
+	Time     template.HTML
+	Event    string
+	Handler  string
+	Error    template.HTML
+	Attempts int
+}
+
+func newDeadLetterArgs(letter DeadLetter) deadLetterArgs {
+	return deadLetterArgs{
+		ID:       letter.ID,
+		Time:     slackDate(letter.Time),
+		Event:    letter.Event,
+		Handler:  letter.Handler,
+		Error:    jsonText(letter.Error),
+		Attempts: letter.Attempts,
+	}
+}
+
+// DeadLetters lists the most recent failed events, count is how many there are in total
+func DeadLetters(letters []DeadLetter, count int) []slack.Block {
+
+	// Header with the number of failed events
+	type header struct {
+		Count int
+		Shown int
+	}
+
+	tpl := renderTemplate(deadLetterAssets, "deadLetterViewsAssets/list.json", header{Count: count, Shown: len(letters)})
+
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("deadLetterViewsAssets/list.json", str, &view)
+
+	// One entry per failed event
+	for _, letter := range letters {
+		tpl := renderTemplate(deadLetterAssets, "deadLetterViewsAssets/item.json", newDeadLetterArgs(letter))
+
+		str, _ = ioutil.ReadAll(&tpl)
+		item := slack.Msg{}
+		unmarshalView("deadLetterViewsAssets/item.json", str, &item)
+
+		view.Blocks.BlockSet = append(view.Blocks.BlockSet, item.Blocks.BlockSet...)
+	}
+
+	return view.Blocks.BlockSet
+}
+
+// DeadLetterDetails shows a failed event and its envelope
+func DeadLetterDetails(letter DeadLetter, envelope string) []slack.Block {
+
+	tpl := renderTemplate(deadLetterAssets, "deadLetterViewsAssets/details.json", newDeadLetterArgs(letter))
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("deadLetterViewsAssets/details.json", str, &view)
+
+	// The envelope is not a template argument, it can contain anything
+	if len(envelope) > maxEnvelopeLength {
+		envelope = envelope[:maxEnvelopeLength] + "…"
+	}
+	code := slack.NewTextBlockObject(slack.MarkdownType, "```"+envelope+"```", false, false)
+
+	return append(view.Blocks.BlockSet, slack.NewSectionBlock(code, nil, nil))
+}
+
+// DeadLetterReplayed confirms the handler of a failed event succeeded
+func DeadLetterReplayed(id string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		ID string
+	}
+
+	tpl := renderTemplate(deadLetterAssets, "deadLetterViewsAssets/replayed.json", args{ID: id})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("deadLetterViewsAssets/replayed.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// DeadLetterReplayFailed tells the handler of a failed event failed again
+func DeadLetterReplayFailed(id string, reason string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		ID    string
+		Error template.HTML
+	}
+
+	tpl := renderTemplate(deadLetterAssets, "deadLetterViewsAssets/replayFailed.json", args{ID: id, Error: jsonText(reason)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("deadLetterViewsAssets/replayFailed.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// DeadLettersPurged confirms the failed events are forgotten
+func DeadLettersPurged(count int) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Count int
+	}
+
+	tpl := renderTemplate(deadLetterAssets, "deadLetterViewsAssets/purged.json", args{Count: count})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("deadLetterViewsAssets/purged.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
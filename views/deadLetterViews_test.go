@@ -0,0 +1,68 @@
+package views
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestDeadLetters(t *testing.T) {
+	letters := []DeadLetter{
+		{
+			ID:       "b415ea3c",
+			Time:     time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC),
+			Event:    "events_api app_home_opened",
+			Handler:  "controllers.(*AppHomeController).publishHomeTabView",
+			Error:    `invalid "view":` + "\nunknown block",
+			Attempts: 3,
+		},
+	}
+
+	blocks := DeadLetters(letters, 5)
+	if len(blocks) != 2 {
+		t.Fatalf("DeadLetters() = %v blocks, want 2", len(blocks))
+	}
+
+	if got := blocks[0].(*slack.SectionBlock).Text.Text; got != "*5 failed event(s)*, the 1 most recent:" {
+		t.Errorf("DeadLetters() header = %v", got)
+	}
+
+	want := "`b415ea3c` *events_api app_home_opened* <!date^1620475200^{date_short_pretty} {time}|May 8, 2021 12:00 UTC>\n" +
+		"controllers.(*AppHomeController).publishHomeTabView failed 3 time(s): invalid \"view\":\nunknown block"
+	if got := blocks[1].(*slack.SectionBlock).Text.Text; got != want {
+		t.Errorf("DeadLetters() = %v, want %v", got, want)
+	}
+
+	// Nothing failed
+	if blocks := DeadLetters(nil, 0); len(blocks) != 1 {
+		t.Errorf("DeadLetters(nil) = %v blocks, want 1", len(blocks))
+	}
+}
+
+func TestDeadLetterDetails(t *testing.T) {
+	letter := DeadLetter{ID: "b415ea3c", Event: "slash_commands /rocket", Error: "internal_error", Attempts: 1}
+
+	blocks := DeadLetterDetails(letter, strings.Repeat("x", 4000))
+	if len(blocks) != 2 {
+		t.Fatalf("DeadLetterDetails() = %v blocks, want 2", len(blocks))
+	}
+
+	if got := blocks[0].(*slack.SectionBlock).Fields[5].Text; got != "*Error*\ninternal_error" {
+		t.Errorf("DeadLetterDetails() error = %v", got)
+	}
+
+	if got := len(blocks[1].(*slack.SectionBlock).Text.Text); got > 3000 {
+		t.Errorf("the envelope is %d characters long", got)
+	}
+}
+
+func TestDeadLetterReplayFailed(t *testing.T) {
+	blocks := DeadLetterReplayFailed("b415ea3c", "internal_error")
+
+	want := ":x: The event `b415ea3c` failed again: internal_error"
+	if got := blocks[0].(*slack.SectionBlock).Text.Text; got != want {
+		t.Errorf("DeadLetterReplayFailed() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,31 @@
+package views
+
+import (
+	"embed"
+	"io/ioutil"
+
+	"github.com/slack-go/slack"
+)
+
+//go:embed errorViewsAssets/*
+var errorAssets embed.FS
+
+// SomethingWentWrong tells the user their action failed
+// ref lets the admins find the error in the logs
+func SomethingWentWrong(ref string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Ref string
+	}
+
+	tpl := renderTemplate(errorAssets, "errorViewsAssets/somethingWentWrong.json", args{Ref: ref})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("errorViewsAssets/somethingWentWrong.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
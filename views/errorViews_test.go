@@ -0,0 +1,19 @@
+package views
+
+import (
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSomethingWentWrong(t *testing.T) {
+	blocks := SomethingWentWrong("a1b2c3d4")
+
+	if len(blocks) != 2 {
+		t.Fatalf("SomethingWentWrong() = %v blocks, want 2", len(blocks))
+	}
+
+	if text := blocks[0].(*slack.SectionBlock).Text.Text; text != ":boom: Something went wrong (ref: `a1b2c3d4`)" {
+		t.Errorf("Text = %q", text)
+	}
+}
@@ -2,8 +2,10 @@ package views
 
 import (
 	"embed"
-	"encoding/json"
+	"html/template"
+	"io/fs"
 	"io/ioutil"
+	"path"
 
 	"github.com/slack-go/slack"
 )
@@ -11,22 +13,65 @@ import (
 //go:embed greetingViewsAssets/*
 var greetingAssets embed.FS
 
+// DefaultGreetingTemplate is used when the greeting policy does not select a template
+const DefaultGreetingTemplate = "greeting.json"
+
 func GreetingMessage(user string) []slack.Block {
+	return GreetingMessageFromTemplate(DefaultGreetingTemplate, user)
+}
+
+// HasGreetingTemplate checks that a template exists in greetingViewsAssets
+func HasGreetingTemplate(file string) bool {
+	_, err := fs.Stat(greetingAssets, path.Join("greetingViewsAssets", file))
+	return err == nil
+}
+
+func GreetingMessageFromTemplate(file string, user string) []slack.Block {
 
 	// we need a stuct to hold template arguments
 	type args struct {
-		User string
+		User template.HTML
+	}
+
+	if file == "" {
+		file = DefaultGreetingTemplate
 	}
 
-	tpl := renderTemplate(greetingAssets, "greetingViewsAssets/greeting.json", args{User: user})
+	tpl := renderTemplate(greetingAssets, path.Join("greetingViewsAssets", file), args{User: displayName(user)})
 
 	// we convert the view into a message struct
 	view := slack.Msg{}
 
 	str, _ := ioutil.ReadAll(&tpl)
-	json.Unmarshal(str, &view)
+	unmarshalView(file, str, &view)
 
 	// We only return the block because of the way the PostEphemeral function works
 	// we are going to use slack.MsgOptionBlocks in the controller
 	return view.Blocks.BlockSet
 }
+
+func BuddyIntroductionMessage(buddy string, mentee string, channel string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Buddy   template.HTML
+		Mentee  template.HTML
+		Channel template.HTML
+	}
+
+	my_args := args{
+		Buddy:   userMention(buddy),
+		Mentee:  userMention(mentee),
+		Channel: channelMention(channel),
+	}
+
+	tpl := renderTemplate(greetingAssets, "greetingViewsAssets/buddy.json", my_args)
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("greetingViewsAssets/buddy.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
@@ -36,3 +36,84 @@ func TestGreetingMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestBuddyIntroductionMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		buddy   string
+		mentee  string
+		channel string
+		want    []slack.Block
+	}{
+		{
+			name:    "Buddy and mentee are mentioned",
+			buddy:   "U0000001",
+			mentee:  "U0000002",
+			channel: "C0000001",
+			want: []slack.Block{
+				&slack.SectionBlock{
+					Type: slack.MBTSection,
+					Text: &slack.TextBlockObject{
+						Type: "mrkdwn",
+						Text: "Hi <@U0000002> :wave: meet <@U0000001>, your buddy in <#C0000001>!",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Block section 0
+			if diff := deep.Equal(BuddyIntroductionMessage(tt.buddy, tt.mentee, tt.channel)[0], tt.want[0]); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestGreetingMessage_Fallback(t *testing.T) {
+	tests := []struct {
+		name string
+		user string
+		want string
+	}{
+		{
+			name: "Unknown users are mentioned",
+			user: "<@U0123456>",
+			want: "Hi <@U0123456> :wave:",
+		},
+		{
+			name: "Names are escaped",
+			user: "<b>David</b>",
+			want: "Hi &lt;b&gt;David&lt;/b&gt; :wave:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section := GreetingMessage(tt.user)[0].(*slack.SectionBlock)
+			if section.Text.Text != tt.want {
+				t.Errorf("GreetingMessage() = %v, want %v", section.Text.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasGreetingTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want bool
+	}{
+		{name: "Default template", file: DefaultGreetingTemplate, want: true},
+		{name: "Guest template", file: "greeting_guest.json", want: true},
+		{name: "External template", file: "greeting_external.json", want: true},
+		{name: "Unknown template", file: "missing.json", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasGreetingTemplate(tt.file); got != tt.want {
+				t.Errorf("HasGreetingTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package views
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+//go:embed launchViewsAssets/*
+var launchAssets embed.FS
+
+// LaunchSummary is an entry of the launch history
+type LaunchSummary struct {
+	Requester string
+	Approvers []string
+	Rocket    string
+	Channel   string
+	Countdown int
+	Outcome   string
+	Timestamp time.Time
+}
+
+// LeaderboardEntry is a user or a channel with its number of launches
+type LeaderboardEntry struct {
+	ID       string
+	Launches int
+}
+
+// outcomeEmojis illustrates how a launch ended
+var outcomeEmojis = map[string]string{
+	"launched":  ":rocket:",
+	"aborted":   ":octagonal_sign:",
+	"denied":    ":no_entry:",
+	"in_flight": ":hourglass_flowing_sand:",
+}
+
+// LaunchHistoryBlocks shows the recent launches followed by the leaderboards
+func LaunchHistoryBlocks(recent []LaunchSummary, users []LeaderboardEntry, channels []LeaderboardEntry) []slack.Block {
+	blocks := []slack.Block{}
+
+	if len(recent) == 0 {
+		return append(blocks, readLaunchBlocks("launchViewsAssets/empty.json", nil)...)
+	}
+
+	// One entry per launch
+	type args struct {
+		Emoji     string
+		Rocket    template.HTML
+		Outcome   string
+		Channel   template.HTML
+		Requester template.HTML
+		Approvers template.HTML
+		When      template.HTML
+		Countdown int
+	}
+
+	for _, launch := range recent {
+		my_args := args{
+			Emoji:     outcomeEmojis[launch.Outcome],
+			Rocket:    jsonText(launch.Rocket),
+			Outcome:   outcomeText(launch.Outcome),
+			Channel:   channelMention(launch.Channel),
+			Requester: userMention(launch.Requester),
+			When:      slackDate(launch.Timestamp),
+			Countdown: launch.Countdown,
+		}
+
+		for i, approver := range launch.Approvers {
+			if i > 0 {
+				my_args.Approvers += ", "
+			}
+			my_args.Approvers += userMention(approver)
+		}
+
+		blocks = append(blocks, readLaunchBlocks("launchViewsAssets/launch.json", my_args)...)
+	}
+
+	// Rankings
+	type leaderboard struct {
+		Users    template.HTML
+		Channels template.HTML
+	}
+
+	return append(blocks, readLaunchBlocks("launchViewsAssets/leaderboard.json", leaderboard{
+		Users:    ranking(users, userMention),
+		Channels: ranking(channels, channelMention),
+	})...)
+}
+
+// AppHomeLaunchesBlocks is the Launches section of the home tab
+func AppHomeLaunchesBlocks(recent []LaunchSummary, users []LeaderboardEntry, channels []LeaderboardEntry) []slack.Block {
+	blocks := readLaunchBlocks("launchViewsAssets/header.json", nil)
+
+	return append(blocks, LaunchHistoryBlocks(recent, users, channels)...)
+}
+
+func outcomeText(outcome string) string {
+	if outcome == "in_flight" {
+		return "counting down"
+	}
+
+	return outcome
+}
+
+// ranking formats a leaderboard as one line per entry
+func ranking(entries []LeaderboardEntry, mention func(string) template.HTML) template.HTML {
+	if len(entries) == 0 {
+		return "-"
+	}
+
+	var lines template.HTML
+	for i, entry := range entries {
+		// the line break is escaped as it is rendered inside a JSON string
+		if i > 0 {
+			lines += `\n`
+		}
+		lines += template.HTML(fmt.Sprintf("%d. ", i+1)) + mention(entry.ID) + template.HTML(fmt.Sprintf(" (%d)", entry.Launches))
+	}
+
+	return lines
+}
+
+func readLaunchBlocks(file string, args interface{}) []slack.Block {
+	tpl := renderTemplate(launchAssets, file, args)
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView(file, str, &view)
+
+	return view.Blocks.BlockSet
+}
@@ -0,0 +1,51 @@
+package views
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestLaunchHistoryBlocks(t *testing.T) {
+	recent := []LaunchSummary{
+		{
+			Requester: "U1",
+			Approvers: []string{"U2", "U3"},
+			Rocket:    "Starship",
+			Channel:   "C1",
+			Countdown: 10,
+			Outcome:   "launched",
+			Timestamp: time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	users := []LeaderboardEntry{{ID: "U1", Launches: 3}, {ID: "U2", Launches: 1}}
+
+	blocks := LaunchHistoryBlocks(recent, users, nil)
+	if len(blocks) != 3 {
+		t.Fatalf("LaunchHistoryBlocks() = %v blocks, want 3", len(blocks))
+	}
+
+	want := ":rocket: *Starship* launched in <#C1>, requested by <@U1> and approved by <@U2>, <@U3>"
+	if got := blocks[0].(*slack.SectionBlock).Text.Text; got != want {
+		t.Errorf("launch = %v, want %v", got, want)
+	}
+
+	want = "<!date^1620475200^{date_short_pretty} {time}|May 8, 2021 12:00 UTC> · 10s count down"
+	if got := blocks[1].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject).Text; got != want {
+		t.Errorf("context = %v, want %v", got, want)
+	}
+
+	leaderboard := blocks[2].(*slack.SectionBlock).Fields
+	if want := "*Top launchers*\n1. <@U1> (3)\n2. <@U2> (1)"; leaderboard[0].Text != want {
+		t.Errorf("users = %q, want %q", leaderboard[0].Text, want)
+	}
+	if want := "*Top channels*\n-"; leaderboard[1].Text != want {
+		t.Errorf("channels = %q, want %q", leaderboard[1].Text, want)
+	}
+
+	// The home tab gets a header and an invitation when nothing was launched
+	if blocks := AppHomeLaunchesBlocks(nil, nil, nil); len(blocks) != 2 {
+		t.Errorf("AppHomeLaunchesBlocks() = %v blocks, want 2", len(blocks))
+	}
+}
@@ -0,0 +1,137 @@
+package views
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// Define Action_id as constant so we can refet to them in the controller
+	OnboardingOptOutActionID = "onboarding_opt_out"
+	OnboardingOptOutBlockID  = "onboarding_opt_out"
+)
+
+//go:embed onboardingViewsAssets/*
+var onboardingAssets embed.FS
+
+// OnboardingStep is a message of the onboarding sequence
+// Day is the number of days after the user joined the workspace
+type OnboardingStep struct {
+	Day      int    `json:"day"`
+	Template string `json:"template"`
+}
+
+// OnboardingSchedule is the list of messages sent to a new member
+// Templates are resolved relative to the schedule definition file
+type OnboardingSchedule struct {
+	Steps []OnboardingStep `json:"steps"`
+
+	fs  fs.FS
+	dir string
+}
+
+// LoadOnboardingSchedule reads a schedule definition file
+// When no file is provided we use the schedule embedded in onboardingViewsAssets
+func LoadOnboardingSchedule(file string) (OnboardingSchedule, error) {
+	if file == "" {
+		return readOnboardingSchedule(onboardingAssets, "onboardingViewsAssets/schedule.json")
+	}
+
+	return readOnboardingSchedule(os.DirFS(filepath.Dir(file)), filepath.Base(file))
+}
+
+func readOnboardingSchedule(fsys fs.FS, file string) (OnboardingSchedule, error) {
+	schedule := OnboardingSchedule{
+		fs:  fsys,
+		dir: path.Dir(file),
+	}
+
+	str, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return schedule, err
+	}
+
+	err = json.Unmarshal(str, &schedule)
+	if err != nil {
+		return schedule, err
+	}
+
+	// A missing or invalid template is reported at startup rather than when a member joins
+	for _, step := range schedule.Steps {
+		if step.Template == "" {
+			return schedule, fmt.Errorf("the onboarding message of day %d has no template", step.Day)
+		}
+
+		if _, err := template.ParseFS(fsys, path.Join(schedule.dir, step.Template)); err != nil {
+			return schedule, fmt.Errorf("the onboarding message of day %d: %w", step.Day, err)
+		}
+	}
+
+	return schedule, nil
+}
+
+// OnboardingPending reads the IDs of the scheduled messages carried by the opt out button
+func OnboardingPending(value string) []string {
+	return strings.Split(value, ",")[1:]
+}
+
+// OnboardingMessage is a message of the sequence, pending are the IDs of the messages scheduled after it
+// the opt out button carries them in its value so they can be deleted, it starts with the action ID to never be empty
+func OnboardingMessage(schedule OnboardingSchedule, step OnboardingStep, user string, pending []string) []slack.Block {
+	// we need a stuct to hold template arguments
+	type args struct {
+		User     template.HTML
+		Day      int
+		ActionID string
+		BlockID  string
+		Value    string
+	}
+
+	my_args := args{
+		User:     displayName(user),
+		Day:      step.Day,
+		ActionID: OnboardingOptOutActionID,
+		BlockID:  OnboardingOptOutBlockID,
+		Value:    strings.Join(append([]string{OnboardingOptOutActionID}, pending...), ","),
+	}
+
+	tpl := renderTemplate(schedule.fs, path.Join(schedule.dir, step.Template), my_args)
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView(step.Template, str, &view)
+
+	// We only return the block because of the way the PostMessage function works
+	// we are going to use slack.MsgOptionBlocks in the controller
+	return view.Blocks.BlockSet
+}
+
+func OnboardingOptOutMessage(user string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		User template.HTML
+	}
+
+	tpl := renderTemplate(onboardingAssets, "onboardingViewsAssets/optout.json", args{User: displayName(user)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("onboardingViewsAssets/optout.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
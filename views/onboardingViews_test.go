@@ -0,0 +1,125 @@
+package views
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/slack-go/slack"
+)
+
+func TestLoadOnboardingSchedule(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "custom.json"), []byte(`{"steps":[{"day":2,"template":"hello.json"}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "hello.json"), []byte(`{"blocks":[]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "typo.json"), []byte(`{"steps":[{"day":2,"template":"helo.json"}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "unnamed.json"), []byte(`{"steps":[{"day":2}]}`), 0644)
+
+	tests := []struct {
+		name    string
+		file    string
+		want    []OnboardingStep
+		wantErr bool
+	}{
+		{
+			name: "Default schedule is embedded",
+			file: "",
+			want: []OnboardingStep{
+				{Day: 0, Template: "day0.json"},
+				{Day: 1, Template: "day1.json"},
+				{Day: 7, Template: "day7.json"},
+			},
+		},
+		{
+			name: "Custom schedule file",
+			file: filepath.Join(dir, "custom.json"),
+			want: []OnboardingStep{
+				{Day: 2, Template: "hello.json"},
+			},
+		},
+		{
+			name:    "Missing schedule file",
+			file:    filepath.Join(dir, "missing.json"),
+			wantErr: true,
+		},
+		{
+			name:    "Unknown template",
+			file:    filepath.Join(dir, "typo.json"),
+			wantErr: true,
+		},
+		{
+			name:    "Missing template",
+			file:    filepath.Join(dir, "unnamed.json"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadOnboardingSchedule(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadOnboardingSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := deep.Equal(got.Steps, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestOnboardingMessage(t *testing.T) {
+	schedule, err := LoadOnboardingSchedule("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		step OnboardingStep
+		user string
+		want []slack.Block
+	}{
+		{
+			name: "User name is added",
+			step: schedule.Steps[0],
+			user: "David",
+			want: []slack.Block{
+				&slack.SectionBlock{
+					Type: slack.MBTSection,
+					Text: &slack.TextBlockObject{
+						Type: "mrkdwn",
+						Text: "Welcome to the team David :tada:",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := OnboardingMessage(schedule, tt.step, tt.user, []string{"Q1", "Q7"})
+
+			// Block section 0
+			if diff := deep.Equal(blocks[0], tt.want[0]); diff != nil {
+				t.Error(diff)
+			}
+
+			// The last block let the user opt out
+			actions, ok := blocks[len(blocks)-1].(*slack.ActionBlock)
+			if !ok {
+				t.Fatalf("expected an action block, got %T", blocks[len(blocks)-1])
+			}
+			if actions.BlockID != OnboardingOptOutBlockID {
+				t.Errorf("BlockID = %v, want %v", actions.BlockID, OnboardingOptOutBlockID)
+			}
+
+			// The button carries the messages scheduled after this one
+			button := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+			if diff := deep.Equal(OnboardingPending(button.Value), []string{"Q1", "Q7"}); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
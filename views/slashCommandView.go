@@ -2,7 +2,9 @@ package views
 
 import (
 	"embed"
+	"encoding/base64"
 	"encoding/json"
+	"html/template"
 	"io/ioutil"
 
 	"github.com/slack-go/slack"
@@ -10,25 +12,105 @@ import (
 
 const (
 	// Define Action_id as constant so we can refet to them in the controller
-	RocketAnnoncementActionID = "rocket_launch_approved"
-	RocketAnnoncementBlockID  = "rocket_annoncement"
+	RocketAnnoncementActionID  = "rocket_launch_approved"
+	RocketAnnoncementBlockID   = "rocket_annoncement"
+	RocketDenyActionID         = "rocket_launch_rejected"
+	RocketDenyReasonCallbackID = "rocket_deny_reason"
+	RocketDenyReasonBlockID    = "deny_reason"
+	RocketDenyReasonActionID   = "reason"
+	RocketAbortActionID        = "rocket_launch_aborted"
+	RocketSelectActionID       = "rocket_select"
 )
 
 //go:embed slackCommandAssets/*
 var slashCommandAssets embed.FS
 
-func LaunchRocketAnnoncement(number int) []slack.Block {
+// RocketLaunch holds the parameters of the /rocket command
+type RocketLaunch struct {
+	Count  int    `json:"count"`
+	Rocket string `json:"rocket"`
+	Silent bool   `json:"silent,omitempty"`
+	// RequestID is the launch request waiting for approvals
+	RequestID string `json:"request_id,omitempty"`
+	// RecordID is the entry of the launch history
+	RecordID string `json:"record_id,omitempty"`
+	// CountdownID is set once the count down is running so it can be aborted
+	CountdownID string `json:"countdown_id,omitempty"`
+}
+
+// Encode the launch so it fits in the value of a button
+// base64 keeps the value safe from the template escaping
+func (l RocketLaunch) Encode() string {
+	str, _ := json.Marshal(l)
+	return base64.RawURLEncoding.EncodeToString(str)
+}
+
+// DecodeRocketLaunch reads the launch from the value of a button
+func DecodeRocketLaunch(value string) (RocketLaunch, error) {
+	launch := RocketLaunch{}
+
+	str, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return launch, err
+	}
+
+	err = json.Unmarshal(str, &launch)
+
+	return launch, err
+}
+
+// RocketApproval is the progress of the approvals of a launch
+// Required is only shown when the launch needs more than one approval
+type RocketApproval struct {
+	Requester string
+	Approvers []string
+	Required  int
+}
+
+// LaunchRocketAnnoncement asks to approve a launch
+// the rocket can be changed with the select until the launch is approved
+func LaunchRocketAnnoncement(launch RocketLaunch, description string, approval RocketApproval) []slack.Block {
 	// we need a stuct to hold template arguments
 	type args struct {
-		Number   int
-		ActionID string
-		BlockID  string
+		Number         int
+		Rocket         template.HTML
+		Description    template.HTML
+		ActionID       string
+		DenyActionID   string
+		SelectActionID string
+		BlockID        string
+		Value          string
+		Requester      template.HTML
+		Approvers      template.HTML
+		Approved       int
+		Required       int
 	}
 
 	my_args := args{
-		Number:   number,
-		ActionID: RocketAnnoncementActionID,
-		BlockID:  RocketAnnoncementBlockID,
+		Number:         launch.Count,
+		Rocket:         jsonText(launch.Rocket),
+		Description:    jsonText(description),
+		ActionID:       RocketAnnoncementActionID,
+		DenyActionID:   RocketDenyActionID,
+		SelectActionID: RocketSelectActionID,
+		BlockID:        RocketAnnoncementBlockID,
+		Value:          launch.Encode(),
+		Approved:       len(approval.Approvers),
+	}
+
+	if approval.Requester != "" {
+		my_args.Requester = userMention(approval.Requester)
+	}
+
+	if approval.Required > 1 {
+		my_args.Required = approval.Required
+	}
+
+	for i, approver := range approval.Approvers {
+		if i > 0 {
+			my_args.Approvers += ", "
+		}
+		my_args.Approvers += userMention(approver)
 	}
 
 	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/annnoncement.json", my_args)
@@ -37,7 +119,7 @@ func LaunchRocketAnnoncement(number int) []slack.Block {
 	view := slack.Msg{}
 
 	str, _ := ioutil.ReadAll(&tpl)
-	json.Unmarshal(str, &view)
+	unmarshalView("slackCommandAssets/annnoncement.json", str, &view)
 
 	// We only return the block because of the way the PostEphemeral function works
 	// we are going to use slack.MsgOptionBlocks in the controller
@@ -45,22 +127,325 @@ func LaunchRocketAnnoncement(number int) []slack.Block {
 
 }
 
-func LaunchRocket(number int) []slack.Block {
+// LaunchRocket shows a frame of the count down
+// the Abort button is shown until the rocket is launched
+func LaunchRocket(number int, image string, launch RocketLaunch) []slack.Block {
 
 	// we need a stuct to hold template arguments
 	type args struct {
-		Number int
+		Number        int
+		Image         string
+		AbortActionID string
+		Value         string
 	}
 
-	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/rocket.json", args{Number: number})
+	my_args := args{
+		Number:        number,
+		Image:         image,
+		AbortActionID: RocketAbortActionID,
+	}
+
+	if number > 0 && launch.CountdownID != "" {
+		my_args.Value = launch.Encode()
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/rocket.json", my_args)
 
 	// we convert the view into a message struct
 	view := slack.Msg{}
 
 	str, _ := ioutil.ReadAll(&tpl)
-	json.Unmarshal(str, &view)
+	unmarshalView("slackCommandAssets/rocket.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// RocketOption is a rocket of the catalog offered by the select of the announcement
+type RocketOption struct {
+	Name        string
+	Description string
+}
+
+// RocketOptions answers the options load of the rocket select
+func RocketOptions(rockets []RocketOption) slack.OptionsResponse {
+	options := slack.OptionsResponse{Options: []*slack.OptionBlockObject{}}
+
+	for _, rocket := range rockets {
+		var description *slack.TextBlockObject
+		if rocket.Description != "" {
+			description = slack.NewTextBlockObject(slack.PlainTextType, rocket.Description, false, false)
+		}
+
+		options.Options = append(options.Options, slack.NewOptionBlockObject(
+			rocket.Name,
+			slack.NewTextBlockObject(slack.PlainTextType, rocket.Name, false, false),
+			description,
+		))
+	}
+
+	return options
+}
+
+// LaunchRocketAborted replaces the count down once the launch is aborted
+func LaunchRocketAborted(user string, rocket string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		User   template.HTML
+		Rocket template.HTML
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/aborted.json", args{User: userMention(user), Rocket: jsonText(rocket)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/aborted.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// SlashCommandUsage explains why the command line is invalid
+func SlashCommandUsage(reason string, usage string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Reason template.HTML
+		Usage  template.HTML
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/usage.json", args{Reason: jsonText(reason), Usage: jsonText(usage)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/usage.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// CommandHelp is a line of the help of a slash command
+type CommandHelp struct {
+	Usage       string
+	Description string
+}
+
+// SlashCommandHelp lists the usage of a slash command and of its subcommands
+func SlashCommandHelp(command string, entries []CommandHelp) []slack.Block {
+
+	// Header with the name of the command
+	type header struct {
+		Command string
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/help.json", header{Command: command})
+
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/help.json", str, &view)
+
+	// One entry per command
+	for _, entry := range entries {
+		type line struct {
+			Usage       template.HTML
+			Description template.HTML
+		}
+
+		tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/helpItem.json", line{Usage: jsonText(entry.Usage), Description: jsonText(entry.Description)})
+
+		str, _ = ioutil.ReadAll(&tpl)
+		item := slack.Msg{}
+		unmarshalView("slackCommandAssets/helpItem.json", str, &item)
+
+		view.Blocks.BlockSet = append(view.Blocks.BlockSet, item.Blocks.BlockSet...)
+	}
+
+	return view.Blocks.BlockSet
+}
+
+// SlashCommandError explains why a slash command did not run
+func SlashCommandError(command string, reason string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Command string
+		Reason  template.HTML
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/commandError.json", args{Command: command, Reason: jsonText(reason)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/commandError.json", str, &view)
 
-	// We only return the block because of the way the PostEphemeral function works
-	// we are going to use slack.MsgOptionBlocks in the controller
 	return view.Blocks.BlockSet
 }
+
+// ScheduledRocketLaunch is a launch waiting for its time
+// At is already formatted in the timezone of the user reading it
+type ScheduledRocketLaunch struct {
+	ID        string
+	Requester string
+	Rocket    string
+	At        string
+}
+
+// RocketLaunchScheduled confirms a launch is scheduled
+func RocketLaunchScheduled(launch ScheduledRocketLaunch) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		ID     string
+		Rocket template.HTML
+		At     string
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/scheduled.json", args{ID: launch.ID, Rocket: jsonText(launch.Rocket), At: launch.At})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/scheduled.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// ScheduledRocketLaunches lists the launches scheduled in a channel
+func ScheduledRocketLaunches(launches []ScheduledRocketLaunch) []slack.Block {
+
+	// Header with the number of launches
+	type header struct {
+		Count int
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/scheduledList.json", header{Count: len(launches)})
+
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/scheduledList.json", str, &view)
+
+	// One entry per launch
+	type args struct {
+		ID        string
+		Requester template.HTML
+		Rocket    template.HTML
+		At        string
+	}
+
+	for _, launch := range launches {
+		my_args := args{
+			ID:        launch.ID,
+			Requester: userMention(launch.Requester),
+			Rocket:    jsonText(launch.Rocket),
+			At:        launch.At,
+		}
+
+		tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/scheduledItem.json", my_args)
+
+		str, _ = ioutil.ReadAll(&tpl)
+		item := slack.Msg{}
+		unmarshalView("slackCommandAssets/scheduledItem.json", str, &item)
+
+		view.Blocks.BlockSet = append(view.Blocks.BlockSet, item.Blocks.BlockSet...)
+	}
+
+	return view.Blocks.BlockSet
+}
+
+// RocketLaunchCancelled confirms a scheduled launch is cancelled
+func RocketLaunchCancelled(launch ScheduledRocketLaunch) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Rocket template.HTML
+		At     string
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/scheduleCancelled.json", args{Rocket: jsonText(launch.Rocket), At: launch.At})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/scheduleCancelled.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// RocketApprovalError explains to a user why their approval was refused
+func RocketApprovalError(reason string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		Reason template.HTML
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/approvalError.json", args{Reason: jsonText(reason)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/approvalError.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// LaunchRocketDenied replaces the announcement once the launch is denied
+func LaunchRocketDenied(user string, rocket string, reason string) []slack.Block {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		User   template.HTML
+		Rocket template.HTML
+		Reason template.HTML
+	}
+
+	// the reason is typed by the user, it can hold quotes, line breaks or mentions
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/denied.json", args{User: userMention(user), Rocket: jsonText(rocket), Reason: mrkdwnText(reason)})
+
+	// we convert the view into a message struct
+	view := slack.Msg{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/denied.json", str, &view)
+
+	return view.Blocks.BlockSet
+}
+
+// RocketDenyReasonModal asks for the reason of a denied launch
+// metadata is sent back by slack when the modal is submitted
+func RocketDenyReasonModal(metadata string) slack.ModalViewRequest {
+
+	// we need a stuct to hold template arguments
+	type args struct {
+		CallbackID string
+		BlockID    string
+		ActionID   string
+	}
+
+	my_args := args{
+		CallbackID: RocketDenyReasonCallbackID,
+		BlockID:    RocketDenyReasonBlockID,
+		ActionID:   RocketDenyReasonActionID,
+	}
+
+	tpl := renderTemplate(slashCommandAssets, "slackCommandAssets/denyReasonModal.json", my_args)
+
+	view := slack.ModalViewRequest{}
+
+	str, _ := ioutil.ReadAll(&tpl)
+	unmarshalView("slackCommandAssets/denyReasonModal.json", str, &view)
+
+	// metadata is not rendered by the template to avoid escaping it
+	view.PrivateMetadata = metadata
+
+	return view
+}
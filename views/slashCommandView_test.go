@@ -10,12 +10,12 @@ import (
 func TestLaunchRocketAnnoncement(t *testing.T) {
 	tests := []struct {
 		name   string
-		number int
+		launch RocketLaunch
 		want   []slack.Block
 	}{
 		{
 			name:   "Count down is correct",
-			number: 3,
+			launch: RocketLaunch{Count: 3, Rocket: "Falcon 9"},
 			want: []slack.Block{
 				&slack.SectionBlock{
 					Type: slack.MBTSection,
@@ -36,11 +36,269 @@ func TestLaunchRocketAnnoncement(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			blocks := LaunchRocketAnnoncement(tt.number)
+			blocks := LaunchRocketAnnoncement(tt.launch, "", RocketApproval{})
 
 			if diff := deep.Equal(blocks[1], tt.want[0]); diff != nil {
 				t.Error(diff)
 			}
+
+			// The launch parameters are carried by the approval button
+			actions := blocks[2].(*slack.ActionBlock)
+			got, err := DecodeRocketLaunch(actions.Elements.ElementSet[0].(*slack.ButtonBlockElement).Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(got, tt.launch); diff != nil {
+				t.Error(diff)
+			}
+
+			// Another rocket of the catalog can be picked
+			selected := actions.Elements.ElementSet[2].(*slack.SelectBlockElement)
+			if selected.ActionID != RocketSelectActionID || selected.InitialOption.Value != tt.launch.Rocket {
+				t.Errorf("select = %+v", selected)
+			}
+		})
+	}
+}
+
+func TestDecodeRocketLaunch(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    RocketLaunch
+		wantErr bool
+	}{
+		{
+			name:  "Encoded launch",
+			value: RocketLaunch{Count: 10, Rocket: "Starship", Silent: true}.Encode(),
+			want:  RocketLaunch{Count: 10, Rocket: "Starship", Silent: true},
+		},
+		{
+			name:    "Legacy button value",
+			value:   RocketAnnoncementActionID,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeRocketLaunch(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeRocketLaunch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); !tt.wantErr && diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSlashCommandUsage(t *testing.T) {
+	blocks := SlashCommandUsage("unknown flag --fast", "/rocket [count] [--silent]")
+
+	want := &slack.SectionBlock{
+		Type: slack.MBTSection,
+		Text: &slack.TextBlockObject{
+			Type: "mrkdwn",
+			Text: ":warning: unknown flag --fast",
+		},
+	}
+
+	if diff := deep.Equal(blocks[0], want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestSlashCommandHelp(t *testing.T) {
+	blocks := SlashCommandHelp("/rocket", []CommandHelp{
+		{Usage: "/rocket [count]", Description: "Announce a rocket launch"},
+		{Usage: "/rocket list"},
+	})
+
+	if len(blocks) != 3 {
+		t.Fatalf("SlashCommandHelp() = %v blocks, want 3", len(blocks))
+	}
+
+	if text := blocks[1].(*slack.SectionBlock).Text.Text; text != "`/rocket [count]`\nAnnounce a rocket launch" {
+		t.Errorf("Text = %q", text)
+	}
+	if text := blocks[2].(*slack.SectionBlock).Text.Text; text != "`/rocket list`" {
+		t.Errorf("Text = %q", text)
+	}
+}
+
+func TestLaunchRocketDenied(t *testing.T) {
+	tests := []struct {
+		name       string
+		reason     string
+		wantBlocks int
+		wantReason string
+	}{
+		{name: "Without reason", reason: "", wantBlocks: 1},
+		{name: "With reason", reason: "Too windy", wantBlocks: 2, wantReason: "Too windy"},
+		{name: "With a multiline reason", reason: "Too \"windy\"\nand C:\\rain", wantBlocks: 2, wantReason: "Too \"windy\"\nand C:\\rain"},
+		{name: "With mentions and links", reason: "<!channel> see <https://evil|click> & more", wantBlocks: 2, wantReason: "&lt;!channel&gt; see &lt;https://evil|click&gt; &amp; more"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := LaunchRocketDenied("U0123456", "Starship", tt.reason)
+
+			if len(blocks) != tt.wantBlocks {
+				t.Fatalf("LaunchRocketDenied() blocks = %v, want %v", len(blocks), tt.wantBlocks)
+			}
+
+			want := &slack.SectionBlock{
+				Type: slack.MBTSection,
+				Text: &slack.TextBlockObject{
+					Type: "mrkdwn",
+					Text: ":no_entry: Launch of *Starship* denied by <@U0123456>",
+				},
+			}
+			if diff := deep.Equal(blocks[0], want); diff != nil {
+				t.Error(diff)
+			}
+
+			if tt.reason != "" {
+				got := blocks[1].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject).Text
+				if got != "Reason: "+tt.wantReason {
+					t.Errorf("LaunchRocketDenied() reason = %q, want %q", got, tt.wantReason)
+				}
+			}
+		})
+	}
+}
+
+func TestLaunchRocketAnnoncement_Escaped(t *testing.T) {
+	launch := RocketLaunch{Count: 3, Rocket: `Falcon "Heavy"`}
+
+	blocks := LaunchRocketAnnoncement(launch, "Two\nlines", RocketApproval{})
+	if len(blocks) != 3 {
+		t.Fatalf("LaunchRocketAnnoncement() blocks = %v, want 3", len(blocks))
+	}
+
+	if got := blocks[1].(*slack.SectionBlock).Fields[0].Text; got != "*Rocket:*\nFalcon \"Heavy\"" {
+		t.Errorf("LaunchRocketAnnoncement() rocket = %q", got)
+	}
+}
+
+func TestRocketDenyReasonModal(t *testing.T) {
+	view := RocketDenyReasonModal(`{"decision_id":"1"}`)
+
+	if view.CallbackID != RocketDenyReasonCallbackID {
+		t.Errorf("CallbackID = %v, want %v", view.CallbackID, RocketDenyReasonCallbackID)
+	}
+	if view.PrivateMetadata != `{"decision_id":"1"}` {
+		t.Errorf("PrivateMetadata = %v", view.PrivateMetadata)
+	}
+
+	input := view.Blocks.BlockSet[0].(*slack.InputBlock)
+	if input.BlockID != RocketDenyReasonBlockID || !input.Optional {
+		t.Errorf("the reason should be an optional input, got %+v", input)
+	}
+}
+
+func TestLaunchRocket(t *testing.T) {
+	launch := RocketLaunch{Count: 3, Rocket: "Falcon 9", CountdownID: "1"}
+
+	// The Abort button carries the count down
+	blocks := LaunchRocket(2, "https://example.com/rocket2.png", launch)
+	if len(blocks) != 2 {
+		t.Fatalf("LaunchRocket() = %v blocks, want 2", len(blocks))
+	}
+
+	// The frame comes from the catalog
+	if image := blocks[0].(*slack.ImageBlock).ImageURL; image != "https://example.com/rocket2.png" {
+		t.Errorf("ImageURL = %v", image)
+	}
+
+	button := blocks[1].(*slack.ActionBlock).Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if button.ActionID != RocketAbortActionID {
+		t.Errorf("ActionID = %v, want %v", button.ActionID, RocketAbortActionID)
+	}
+
+	got, err := DecodeRocketLaunch(button.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(got, launch); diff != nil {
+		t.Error(diff)
+	}
+
+	// There is nothing to abort once launched
+	if blocks := LaunchRocket(0, "https://example.com/rocket0.png", launch); len(blocks) != 1 {
+		t.Errorf("LaunchRocket(0) = %v blocks, want 1", len(blocks))
+	}
+}
+
+func TestLaunchRocketAnnoncement_Approvals(t *testing.T) {
+	tests := []struct {
+		name     string
+		approval RocketApproval
+		want     string
+	}{
+		{
+			name:     "Single approval",
+			approval: RocketApproval{Requester: "U1", Required: 1},
+			want:     "Requested by <@U1>",
+		},
+		{
+			name:     "Waiting for approvals",
+			approval: RocketApproval{Requester: "U1", Required: 3},
+			want:     "Requested by <@U1> · Approvals 0/3",
+		},
+		{
+			name:     "Approvers so far",
+			approval: RocketApproval{Requester: "U1", Approvers: []string{"U2", "U3"}, Required: 3},
+			want:     "Requested by <@U1> · Approvals 2/3: <@U2>, <@U3>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := LaunchRocketAnnoncement(RocketLaunch{Count: 3, Rocket: "Falcon 9"}, "", tt.approval)
+
+			context := blocks[3].(*slack.ContextBlock)
+			got := context.ContextElements.Elements[0].(*slack.TextBlockObject).Text
+			if got != tt.want {
+				t.Errorf("LaunchRocketAnnoncement() = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
+
+func TestScheduledRocketLaunches(t *testing.T) {
+	launches := []ScheduledRocketLaunch{
+		{ID: "1", Requester: "U1", Rocket: "Falcon 9", At: "Sat May 8 15:30 CEST"},
+		{ID: "2", Requester: "U2", Rocket: "Starship", At: "Sat May 8 17:00 CEST"},
+	}
+
+	blocks := ScheduledRocketLaunches(launches)
+	if len(blocks) != 3 {
+		t.Fatalf("ScheduledRocketLaunches() = %v blocks, want 3", len(blocks))
+	}
+
+	want := "`2` *Starship* at Sat May 8 17:00 CEST, requested by <@U2>"
+	if got := blocks[2].(*slack.SectionBlock).Text.Text; got != want {
+		t.Errorf("ScheduledRocketLaunches() = %v, want %v", got, want)
+	}
+
+	// Nothing scheduled
+	if blocks := ScheduledRocketLaunches(nil); len(blocks) != 1 {
+		t.Errorf("ScheduledRocketLaunches(nil) = %v blocks, want 1", len(blocks))
+	}
+}
+
+func TestRocketOptions(t *testing.T) {
+	options := RocketOptions([]RocketOption{
+		{Name: "Falcon 9", Description: "Reusable two-stage rocket"},
+		{Name: "Starship"},
+	})
+
+	if len(options.Options) != 2 {
+		t.Fatalf("RocketOptions() = %v options, want 2", len(options.Options))
+	}
+	if options.Options[0].Value != "Falcon 9" || options.Options[0].Description.Text != "Reusable two-stage rocket" {
+		t.Errorf("RocketOptions()[0] = %+v", options.Options[0])
+	}
+	if options.Options[1].Description != nil {
+		t.Errorf("RocketOptions()[1] should not have a description")
+	}
+}
@@ -2,10 +2,21 @@ package views
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"html/template"
 	"io/fs"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
 )
 
+// mentionPattern matches a slack mention such as <@U0123456> or <#C0123456>
+var mentionPattern = regexp.MustCompile(`^<[@#][A-Z0-9]+>$`)
+
 func renderTemplate(fs fs.FS, file string, args interface{}) bytes.Buffer {
 
 	var tpl bytes.Buffer
@@ -24,3 +35,62 @@ func renderTemplate(fs fs.FS, file string, args interface{}) bytes.Buffer {
 
 	return tpl
 }
+
+// userMention formats a user ID so slack displays it as a mention
+// html/template would otherwise escape the angle brackets
+func userMention(user string) template.HTML {
+	return template.HTML("<@" + template.HTMLEscapeString(user) + ">")
+}
+
+// channelMention formats a channel ID so slack displays it as a link
+func channelMention(channel string) template.HTML {
+	return template.HTML("<#" + template.HTMLEscapeString(channel) + ">")
+}
+
+// displayName renders a user name in a template
+// a mention (used when the name is unknown) is kept as is so slack resolves it
+func displayName(name string) template.HTML {
+	if mentionPattern.MatchString(name) {
+		return template.HTML(name)
+	}
+
+	return template.HTML(template.HTMLEscapeString(name))
+}
+
+// slackDate lets slack display a date in the timezone of the user reading it
+func slackDate(t time.Time) template.HTML {
+	return template.HTML(fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", t.Unix(), t.UTC().Format("Jan 2, 2006 15:04 UTC")))
+}
+
+// jsonText renders any text inside a JSON string of a template, e.g. an error with quotes or newlines
+func jsonText(s string) template.HTML {
+	str, _ := json.Marshal(s)
+	return template.HTML(str[1 : len(str)-1])
+}
+
+// mrkdwnEscaper escapes the control characters of slack, e.g. <!channel> would notify the channel
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// mrkdwnText renders a text typed by a user inside a mrkdwn JSON string of a template
+// slack shows it as typed instead of turning it into mentions or links
+func mrkdwnText(s string) template.HTML {
+	return jsonText(mrkdwnEscaper.Replace(s))
+}
+
+// unmarshalView decodes a rendered template into view
+// an invalid JSON is logged rather than silently shown as an empty message
+// The embedded images the view references are replaced by their slack file
+func unmarshalView(file string, str []byte, view interface{}) {
+	if err := json.Unmarshal(str, view); err != nil {
+		log.Printf("ERROR invalid view %s: %v", file, err)
+	}
+
+	switch view := view.(type) {
+	case *slack.Msg:
+		view.Blocks.BlockSet = resolveAssets(view.Blocks.BlockSet)
+	case *slack.HomeTabViewRequest:
+		view.Blocks.BlockSet = resolveAssets(view.Blocks.BlockSet)
+	case *slack.ModalViewRequest:
+		view.Blocks.BlockSet = resolveAssets(view.Blocks.BlockSet)
+	}
+}